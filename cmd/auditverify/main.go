@@ -0,0 +1,64 @@
+// Command auditverify checks the integrity of audit logs written by the
+// logger/audit package.
+//
+// Usage:
+//
+//	auditverify [-key-env NAME] [-expect-hash HASH] FILE...
+//
+// The HMAC key is read from the environment variable named by -key-env, so
+// it doesn't end up in the shell history. -expect-hash compares the hash of
+// the last record with one stored elsewhere, to detect records removed from
+// the end. It exits with status 1 when a file fails verification.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/saipulimdn/gopackkit/logger/audit"
+)
+
+func main() {
+	keyEnv := flag.String("key-env", "", "environment variable holding the HMAC key")
+	expectHash := flag.String("expect-hash", "", "expected hash of the last record")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-key-env NAME] [-expect-hash HASH] FILE...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var key []byte
+	if *keyEnv != "" {
+		value, ok := os.LookupEnv(*keyEnv)
+		if !ok || value == "" {
+			fmt.Fprintf(os.Stderr, "environment variable %s is not set\n", *keyEnv)
+			os.Exit(2)
+		}
+		key = []byte(value)
+	}
+
+	failed := false
+	for _, filename := range flag.Args() {
+		report, err := audit.VerifyFile(filename, key)
+		switch {
+		case err != nil:
+			fmt.Printf("%s: FAILED: %v\n", filename, err)
+			failed = true
+		case *expectHash != "" && report.LastHash != *expectHash:
+			fmt.Printf("%s: FAILED: last hash %s does not match the expected hash, records may have been removed\n", filename, report.LastHash)
+			failed = true
+		default:
+			fmt.Printf("%s: OK, %d records, last seq %d, last hash %s\n", filename, report.Records, report.LastSeq, report.LastHash)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Load loads configuration from environment variables and applies defaults
@@ -61,6 +62,10 @@ func loadStruct(v reflect.Value, t reflect.Type) error {
 
 		// Get environment variable name
 		envName := fieldType.Tag.Get("env")
+		if envName == "-" {
+			// Field can't be set from the environment
+			continue
+		}
 		if envName == "" {
 			// Use field name in uppercase as default
 			envName = strings.ToUpper(fieldType.Name)
@@ -89,6 +94,21 @@ func loadStruct(v reflect.Value, t reflect.Type) error {
 }
 
 func setFieldValue(field reflect.Value, value string) error {
+	// time.Duration is an int64, but is written like "1s" or "500ms". Plain
+	// integers are still read as nanoseconds, as they were before.
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			nanos, intErr := strconv.ParseInt(value, 10, 64)
+			if intErr != nil {
+				return fmt.Errorf("invalid duration value: %s", value)
+			}
+			duration = time.Duration(nanos)
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
 	switch field.Kind() {
 	case reflect.String:
 		field.SetString(value)
@@ -134,6 +154,22 @@ func setFieldValue(field reflect.Value, value string) error {
 			return fmt.Errorf("unsupported slice type: %s", field.Type())
 		}
 
+	case reflect.Map:
+		// Handle string maps (comma-separated key=value pairs)
+		if field.Type().Key().Kind() != reflect.String || field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map type: %s", field.Type())
+		}
+		m := reflect.MakeMap(field.Type())
+		for _, pair := range strings.Split(value, ",") {
+			key, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("invalid key=value pair: %s", pair)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(key)).Convert(field.Type().Key()),
+				reflect.ValueOf(strings.TrimSpace(val)).Convert(field.Type().Elem()))
+		}
+		field.Set(m)
+
 	default:
 		return fmt.Errorf("unsupported field type: %s", field.Kind())
 	}
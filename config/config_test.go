@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"30s", 30 * time.Second},
+		{"1m30s", 90 * time.Second},
+		{"500ms", 500 * time.Millisecond},
+		{"0", 0},
+		// Without a unit the value is in nanoseconds
+		{"1000000000", time.Second},
+		{"-5", -5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("TEST_TIMEOUT", tt.value)
+
+			var cfg struct {
+				Timeout time.Duration `env:"TEST_TIMEOUT"`
+			}
+			if err := LoadFromEnv(&cfg); err != nil {
+				t.Fatalf("LoadFromEnv() error = %v", err)
+			}
+			if cfg.Timeout != tt.want {
+				t.Errorf("Timeout = %s, want %s", cfg.Timeout, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadInvalidDuration(t *testing.T) {
+	t.Setenv("TEST_TIMEOUT", "soon")
+
+	var cfg struct {
+		Timeout time.Duration `env:"TEST_TIMEOUT"`
+	}
+	if err := LoadFromEnv(&cfg); err == nil {
+		t.Error("LoadFromEnv() accepted an invalid duration")
+	}
+}
+
+func TestLoadDurationDefault(t *testing.T) {
+	var cfg struct {
+		Timeout time.Duration `env:"TEST_UNSET_TIMEOUT" default:"2s"`
+	}
+	if err := LoadFromEnv(&cfg); err != nil {
+		t.Fatalf("LoadFromEnv() error = %v", err)
+	}
+	if cfg.Timeout != 2*time.Second {
+		t.Errorf("Timeout = %s, want 2s", cfg.Timeout)
+	}
+}
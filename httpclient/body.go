@@ -0,0 +1,294 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// errBodyConsumed is returned when a streamed body is read a second time
+var errBodyConsumed = errors.New("streamed request body can only be sent once")
+
+// Encoder encodes request bodies, e.g. as JSON, XML or protobuf
+type Encoder interface {
+	// ContentType returns the Content-Type of encoded bodies
+	ContentType() string
+	// Encode encodes v
+	Encode(v interface{}) ([]byte, error)
+}
+
+// JSONEncoder encodes bodies as JSON, the default
+type JSONEncoder struct{}
+
+// ContentType returns application/json
+func (JSONEncoder) ContentType() string { return "application/json" }
+
+// Encode marshals v as JSON
+func (JSONEncoder) Encode(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// XMLEncoder encodes bodies as XML
+type XMLEncoder struct{}
+
+// ContentType returns application/xml
+func (XMLEncoder) ContentType() string { return "application/xml" }
+
+// Encode marshals v as XML
+func (XMLEncoder) Encode(v interface{}) ([]byte, error) { return xml.Marshal(v) }
+
+// Body is a request body with an explicit encoding, built with Raw, Reader,
+// Form, Multipart or Encoded. Bodies that can be read again are resent on
+// every attempt; streamed ones can only be sent once, so requests carrying
+// them are not retried.
+type Body struct {
+	contentType string
+	// data holds the whole body, open streams it instead
+	data []byte
+	open func() (io.Reader, error)
+	// replayable reports whether open can be called more than once
+	replayable bool
+	// length returns the size of the reader returned by open, or -1 when
+	// unknown, so the request can carry a Content-Length
+	length func() int64
+
+	// value is encoded with encoder before the request is sent
+	value   interface{}
+	encoder Encoder
+}
+
+// Raw returns a body sending data as is with the given content type
+func Raw(contentType string, data []byte) *Body {
+	return &Body{contentType: contentType, data: data}
+}
+
+// Reader returns a body streaming r with the given content type. When r is
+// an io.Seeker it is rewound for every attempt, otherwise the request is
+// sent only once. r is not closed.
+//
+// The Content-Length is set when it is known up front: the size of an
+// io.Seeker, or the Len of readers like bytes.Buffer. Other readers are
+// sent with chunked encoding.
+func Reader(contentType string, r io.Reader) *Body {
+	seeker, replayable := r.(io.Seeker)
+	// Hide Close so the transport doesn't close a reader owned by the caller.
+	// This also hides Len, so the length is worked out below.
+	body := struct{ io.Reader }{r}
+
+	length := func() int64 { return -1 }
+	if replayable {
+		length = func() int64 {
+			// open rewinds the reader, so its size is what will be sent
+			end, err := seeker.Seek(0, io.SeekEnd)
+			if err != nil {
+				return -1
+			}
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return -1
+			}
+			return end
+		}
+	} else if lr, ok := r.(interface{ Len() int }); ok {
+		length = func() int64 { return int64(lr.Len()) }
+	}
+
+	var used atomic.Bool
+	return &Body{
+		contentType: contentType,
+		replayable:  replayable,
+		length:      length,
+		open: func() (io.Reader, error) {
+			if replayable {
+				if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+					return nil, fmt.Errorf("failed to rewind request body: %w", err)
+				}
+				return body, nil
+			}
+			if used.Swap(true) {
+				return nil, errBodyConsumed
+			}
+			return body, nil
+		},
+	}
+}
+
+// Form returns an application/x-www-form-urlencoded body
+func Form(values url.Values) *Body {
+	return Raw("application/x-www-form-urlencoded", []byte(values.Encode()))
+}
+
+// Encoded returns a body encoding v with enc instead of Config.Encoder
+func Encoded(enc Encoder, v interface{}) *Body {
+	return &Body{value: v, encoder: enc}
+}
+
+// MultipartPart is a field or file of a multipart body
+type MultipartPart struct {
+	name        string
+	value       string
+	filename    string
+	contentType string
+	r           io.Reader
+}
+
+// FormField returns a multipart form field
+func FormField(name, value string) MultipartPart {
+	return MultipartPart{name: name, value: value}
+}
+
+// FormFile returns a multipart file read from r while the request is sent.
+// When r is an io.Seeker it is rewound for every attempt.
+func FormFile(field, filename string, r io.Reader) MultipartPart {
+	return MultipartPart{name: field, filename: filename, r: r}
+}
+
+// WithContentType sets the Content-Type of a file part, which defaults to
+// application/octet-stream
+func (p MultipartPart) WithContentType(contentType string) MultipartPart {
+	p.contentType = contentType
+	return p
+}
+
+// Multipart returns a multipart/form-data body. Files are streamed, so large
+// uploads are never held in memory.
+func Multipart(parts ...MultipartPart) *Body {
+	// The boundary is part of the content type, so it is fixed for all attempts
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+
+	replayable := true
+	for _, part := range parts {
+		if part.r != nil {
+			if _, ok := part.r.(io.Seeker); !ok {
+				replayable = false
+			}
+		}
+	}
+
+	var (
+		used     atomic.Bool
+		mu       sync.Mutex
+		prevPipe *io.PipeReader
+		prevDone chan struct{}
+	)
+	return &Body{
+		contentType: "multipart/form-data; boundary=" + boundary,
+		replayable:  replayable,
+		open: func() (io.Reader, error) {
+			if !replayable && used.Swap(true) {
+				return nil, errBodyConsumed
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			// Stop the writer of the previous attempt before rewinding the files
+			if prevPipe != nil {
+				_ = prevPipe.Close()
+				<-prevDone
+			}
+
+			pr, pw := io.Pipe()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				// The request closes pr when it is done, which unblocks a pending write
+				pw.CloseWithError(writeMultipart(pw, boundary, parts))
+			}()
+			prevPipe, prevDone = pr, done
+			return pr, nil
+		},
+	}
+}
+
+// writeMultipart writes parts as multipart/form-data to w
+func writeMultipart(w io.Writer, boundary string, parts []MultipartPart) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	for _, part := range parts {
+		if part.r == nil {
+			if err := mw.WriteField(part.name, part.value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if seeker, ok := part.r.(io.Seeker); ok {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to rewind %s: %w", part.filename, err)
+			}
+		}
+
+		contentType := part.contentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(part.name), quoteEscaper.Replace(part.filename)))
+		header.Set("Content-Type", contentType)
+
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(pw, part.r); err != nil {
+			return fmt.Errorf("failed to read %s: %w", part.filename, err)
+		}
+	}
+
+	return mw.Close()
+}
+
+// quoteEscaper escapes Content-Disposition parameters like mime/multipart
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// prepareBody turns the body argument of a request into a Body, encoding
+// values with the configured encoder once for all attempts
+func (c *Client) prepareBody(body interface{}) (*Body, error) {
+	switch b := body.(type) {
+	case nil:
+		return nil, nil
+	case *Body:
+		if b.encoder == nil {
+			return b, nil
+		}
+		return encodeBody(b.encoder, b.value)
+	default:
+		encoder := c.config.Encoder
+		if encoder == nil {
+			encoder = JSONEncoder{}
+		}
+		return encodeBody(encoder, body)
+	}
+}
+
+func encodeBody(enc Encoder, v interface{}) (*Body, error) {
+	data, err := enc.Encode(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	return Raw(enc.ContentType(), data), nil
+}
+
+// reader returns a reader over the body for one attempt
+func (b *Body) reader() (io.Reader, error) {
+	if b.open != nil {
+		return b.open()
+	}
+	return bytes.NewReader(b.data), nil
+}
+
+// canReplay reports whether the body can be sent again
+func (b *Body) canReplay() bool {
+	return b == nil || b.open == nil || b.replayable
+}
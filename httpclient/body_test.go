@@ -0,0 +1,107 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// receivedRequest is what a test server saw of a request
+type receivedRequest struct {
+	contentLength int64
+	chunked       bool
+	body          string
+}
+
+// echoServer records the requests it receives
+func echoServer(t *testing.T) (*httptest.Server, func() []receivedRequest) {
+	t.Helper()
+	var received []receivedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, receivedRequest{
+			contentLength: r.ContentLength,
+			chunked:       len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked",
+			body:          string(body),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []receivedRequest { return received }
+}
+
+func TestReaderContentLength(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "upload.txt")
+	if err := os.WriteFile(file, []byte("file content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// A seeker is rewound, so its whole size is sent even after a read
+	seeker := strings.NewReader("seekable")
+	_, _ = seeker.Read(make([]byte, 3))
+
+	tests := []struct {
+		name   string
+		r      io.Reader
+		length int64
+		body   string
+	}{
+		{"strings.Reader", seeker, 8, "seekable"},
+		{"os.File", f, 12, "file content"},
+		{"bytes.Buffer", bytes.NewBufferString("buffered"), 8, "buffered"},
+		{"empty", bytes.NewReader(nil), 0, ""},
+		{"unknown length", io.MultiReader(strings.NewReader("a"), strings.NewReader("b")), -1, "ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, received := echoServer(t)
+			client := NewWithConfig(Config{})
+
+			if _, err := client.Post(server.URL, Reader("text/plain", tt.r)); err != nil {
+				t.Fatalf("Post() error = %v", err)
+			}
+
+			got := received()[0]
+			if got.body != tt.body {
+				t.Errorf("body = %q, want %q", got.body, tt.body)
+			}
+			if got.contentLength != tt.length {
+				t.Errorf("Content-Length = %d, want %d", got.contentLength, tt.length)
+			}
+			if got.chunked != (tt.length < 0) {
+				t.Errorf("chunked = %v, want chunked only without a known length", got.chunked)
+			}
+		})
+	}
+}
+
+func TestReaderContentLengthOnRetry(t *testing.T) {
+	attempts := 0
+	var lengths []int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		lengths = append(lengths, r.ContentLength)
+		if attempts++; attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := NewWithConfig(Config{Retries: 1})
+	if _, err := client.Put(server.URL, Reader("text/plain", strings.NewReader("retried"))); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if len(lengths) != 2 || lengths[0] != 7 || lengths[1] != 7 {
+		t.Errorf("Content-Length per attempt = %v, want 7 on both", lengths)
+	}
+}
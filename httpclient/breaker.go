@@ -0,0 +1,288 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// breakerBuckets is the number of buckets the failure-rate window is split into
+const breakerBuckets = 10
+
+// ErrCircuitOpen is returned without sending the request while the circuit
+// breaker of the target host is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState represents the state of a circuit breaker
+type CircuitState string
+
+const (
+	// CircuitClosed lets requests through while recording failures
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen rejects requests until the cooldown has passed
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a few trial requests through to probe the host
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// CircuitBreakerConfig holds the circuit breaker configuration. Every host
+// gets its own breaker.
+type CircuitBreakerConfig struct {
+	// FailureRate opens the circuit when the ratio of failed requests in the
+	// window reaches it. Defaults to 0.5.
+	FailureRate float64 `json:"failure_rate" yaml:"failure_rate"`
+	// MinRequests is the number of requests in the window needed before the
+	// failure rate is considered. Defaults to 10.
+	MinRequests int `json:"min_requests" yaml:"min_requests"`
+	// Window is the rolling window failures are counted over. Defaults to 60s.
+	Window time.Duration `json:"window" yaml:"window"`
+	// Cooldown is how long the circuit stays open before trial requests are
+	// let through. Defaults to 30s.
+	Cooldown time.Duration `json:"cooldown" yaml:"cooldown"`
+	// HalfOpenRequests is the number of trial requests that must succeed to
+	// close the circuit again. Defaults to 1.
+	HalfOpenRequests int `json:"half_open_requests" yaml:"half_open_requests"`
+	// IsFailure decides whether an attempt counts as a failure. Defaults to
+	// transport errors and 5xx responses.
+	IsFailure func(resp *Response, err error) bool `json:"-" yaml:"-"`
+	// OnStateChange is called synchronously whenever the circuit of a host
+	// changes state
+	OnStateChange func(host string, from, to CircuitState) `json:"-" yaml:"-"`
+}
+
+// withDefaults fills in unset fields
+func (c CircuitBreakerConfig) withDefaults() CircuitBreakerConfig {
+	if c.FailureRate <= 0 {
+		c.FailureRate = 0.5
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = 10
+	}
+	if c.Window <= 0 {
+		c.Window = 60 * time.Second
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = 30 * time.Second
+	}
+	if c.HalfOpenRequests <= 0 {
+		c.HalfOpenRequests = 1
+	}
+	if c.IsFailure == nil {
+		c.IsFailure = defaultIsFailure
+	}
+	return c
+}
+
+// defaultIsFailure counts transport errors and server errors as failures
+func defaultIsFailure(resp *Response, err error) bool {
+	return err != nil || (resp != nil && resp.StatusCode >= http.StatusInternalServerError)
+}
+
+// breakerBucket counts the requests of one slot of the window
+type breakerBucket struct {
+	slot     int64
+	requests int
+	failures int
+}
+
+// circuitBreaker tracks the health of a single host
+type circuitBreaker struct {
+	host   string
+	config CircuitBreakerConfig
+
+	mu         sync.Mutex
+	state      CircuitState
+	generation uint64
+	openedAt   time.Time
+	buckets    [breakerBuckets]breakerBucket
+	inFlight   int
+	successes  int
+}
+
+func newCircuitBreaker(host string, config CircuitBreakerConfig) *circuitBreaker {
+	return &circuitBreaker{
+		host:   host,
+		config: config,
+		state:  CircuitClosed,
+	}
+}
+
+// allow reports whether a request may be sent. The returned generation must
+// be passed to record or release once the attempt is over.
+func (b *circuitBreaker) allow() (uint64, error) {
+	b.mu.Lock()
+
+	from := b.state
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.config.Cooldown {
+		b.setState(CircuitHalfOpen)
+	}
+
+	var err error
+	switch b.state {
+	case CircuitOpen:
+		err = ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.inFlight >= b.config.HalfOpenRequests {
+			err = ErrCircuitOpen
+		} else {
+			b.inFlight++
+		}
+	}
+
+	generation, to := b.generation, b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return generation, err
+}
+
+// record records the outcome of an attempt allowed in generation
+func (b *circuitBreaker) record(generation uint64, resp *Response, err error) {
+	failure := b.config.IsFailure(resp, err)
+
+	b.mu.Lock()
+
+	from := b.state
+	// Outcomes of attempts started before the last state change are stale
+	if generation == b.generation {
+		switch b.state {
+		case CircuitClosed:
+			bucket := b.bucket(time.Now())
+			bucket.requests++
+			if failure {
+				bucket.failures++
+			}
+			requests, failures := b.totals(time.Now())
+			if requests >= b.config.MinRequests && float64(failures)/float64(requests) >= b.config.FailureRate {
+				b.setState(CircuitOpen)
+			}
+		case CircuitHalfOpen:
+			b.inFlight--
+			if failure {
+				b.setState(CircuitOpen)
+			} else {
+				b.successes++
+				if b.successes >= b.config.HalfOpenRequests {
+					b.setState(CircuitClosed)
+				}
+			}
+		}
+	}
+
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// release gives back a trial slot of an attempt that ended without an
+// outcome, e.g. because its context was canceled
+func (b *circuitBreaker) release(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation == b.generation && b.state == CircuitHalfOpen {
+		b.inFlight--
+	}
+}
+
+// currentState returns the state, taking an elapsed cooldown into account
+func (b *circuitBreaker) currentState() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.config.Cooldown {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// setState switches to state and starts a new generation. The caller must
+// hold b.mu.
+func (b *circuitBreaker) setState(state CircuitState) {
+	b.state = state
+	b.generation++
+	b.inFlight = 0
+	b.successes = 0
+	b.buckets = [breakerBuckets]breakerBucket{}
+	if state == CircuitOpen {
+		b.openedAt = time.Now()
+	}
+}
+
+// notify calls OnStateChange when the state changed
+func (b *circuitBreaker) notify(from, to CircuitState) {
+	if from != to && b.config.OnStateChange != nil {
+		b.config.OnStateChange(b.host, from, to)
+	}
+}
+
+// bucket returns the bucket for now, resetting it if it held an old slot.
+// The caller must hold b.mu.
+func (b *circuitBreaker) bucket(now time.Time) *breakerBucket {
+	slot := b.slot(now)
+	bucket := &b.buckets[slot%breakerBuckets]
+	if bucket.slot != slot {
+		*bucket = breakerBucket{slot: slot}
+	}
+	return bucket
+}
+
+// totals sums the buckets inside the window. The caller must hold b.mu.
+func (b *circuitBreaker) totals(now time.Time) (requests, failures int) {
+	current := b.slot(now)
+	for _, bucket := range b.buckets {
+		if current-bucket.slot < breakerBuckets {
+			requests += bucket.requests
+			failures += bucket.failures
+		}
+	}
+	return requests, failures
+}
+
+func (b *circuitBreaker) slot(now time.Time) int64 {
+	size := int64(b.config.Window / breakerBuckets)
+	if size <= 0 {
+		size = 1
+	}
+	return now.UnixNano() / size
+}
+
+// breaker returns the circuit breaker of host, or nil when disabled
+func (c *Client) breaker(host string) *circuitBreaker {
+	if c.config.CircuitBreaker == nil {
+		return nil
+	}
+
+	c.breakersMu.Lock()
+	defer c.breakersMu.Unlock()
+
+	if c.breakers == nil {
+		c.breakers = make(map[string]*circuitBreaker)
+	}
+	b, ok := c.breakers[host]
+	if !ok {
+		b = newCircuitBreaker(host, c.config.CircuitBreaker.withDefaults())
+		c.breakers[host] = b
+	}
+	return b
+}
+
+// CircuitState returns the circuit breaker state of host ("example.com" or
+// "example.com:8080" as in the request URL). Hosts without requests yet and
+// clients without a circuit breaker report CircuitClosed.
+func (c *Client) CircuitState(host string) CircuitState {
+	if c.config.CircuitBreaker == nil {
+		return CircuitClosed
+	}
+
+	c.breakersMu.Lock()
+	b, ok := c.breakers[host]
+	c.breakersMu.Unlock()
+
+	if !ok {
+		return CircuitClosed
+	}
+	return b.currentState()
+}
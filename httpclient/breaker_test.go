@@ -0,0 +1,248 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// switchServer answers with the status currently stored in status
+func switchServer(t *testing.T, status *atomic.Int32, requests *atomic.Int32) (*httptest.Server, string) {
+	t.Helper()
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	t.Cleanup(server.Close)
+	return server, serverHost(t, server)
+}
+
+// serverHost returns the host:port of server, as used by CircuitState
+func serverHost(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Host
+}
+
+// stateRecorder collects the transitions reported by OnStateChange
+type stateRecorder struct {
+	mu          sync.Mutex
+	transitions []string
+}
+
+func (r *stateRecorder) record(host string, from, to CircuitState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transitions = append(r.transitions, fmt.Sprintf("%s->%s", from, to))
+}
+
+func (r *stateRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.transitions...)
+}
+
+func newBreakerClient(config CircuitBreakerConfig) *Client {
+	return NewWithConfig(Config{Timeout: 5 * time.Second, CircuitBreaker: &config})
+}
+
+func TestCircuitBreakerLifecycle(t *testing.T) {
+	var status, requests atomic.Int32
+	server, host := switchServer(t, &status, &requests)
+
+	var states stateRecorder
+	client := newBreakerClient(CircuitBreakerConfig{
+		FailureRate:   0.5,
+		MinRequests:   4,
+		Cooldown:      50 * time.Millisecond,
+		OnStateChange: states.record,
+	})
+
+	// Two successes and two failures reach the failure rate
+	for _, code := range []int32{http.StatusOK, http.StatusOK, http.StatusInternalServerError} {
+		status.Store(code)
+		if _, err := client.Get(server.URL); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if got := client.CircuitState(host); got != CircuitClosed {
+			t.Fatalf("state = %s before MinRequests, want closed", got)
+		}
+	}
+	_, _ = client.Get(server.URL)
+	if got := client.CircuitState(host); got != CircuitOpen {
+		t.Fatalf("state = %s, want open", got)
+	}
+
+	// While open, requests fail without reaching the server
+	sent := requests.Load()
+	if _, err := client.Get(server.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Get() error = %v, want ErrCircuitOpen", err)
+	}
+	if requests.Load() != sent {
+		t.Error("a request reached the server while the circuit was open")
+	}
+
+	// After the cooldown a failed trial opens the circuit again
+	time.Sleep(60 * time.Millisecond)
+	if got := client.CircuitState(host); got != CircuitHalfOpen {
+		t.Fatalf("state = %s after the cooldown, want half-open", got)
+	}
+	_, _ = client.Get(server.URL)
+	if got := client.CircuitState(host); got != CircuitOpen {
+		t.Fatalf("state = %s after a failed trial, want open", got)
+	}
+
+	// And a successful one closes it
+	time.Sleep(60 * time.Millisecond)
+	status.Store(http.StatusOK)
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("trial Get() error = %v", err)
+	}
+	if got := client.CircuitState(host); got != CircuitClosed {
+		t.Fatalf("state = %s after a successful trial, want closed", got)
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if got := states.get(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+}
+
+func TestCircuitBreakerFailureRate(t *testing.T) {
+	var status, requests atomic.Int32
+	server, host := switchServer(t, &status, &requests)
+	client := newBreakerClient(CircuitBreakerConfig{FailureRate: 0.5, MinRequests: 4})
+
+	// One failure out of four stays below the rate
+	for _, code := range []int32{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusBadGateway} {
+		status.Store(code)
+		_, _ = client.Get(server.URL)
+	}
+	if got := client.CircuitState(host); got != CircuitClosed {
+		t.Errorf("state = %s, want closed", got)
+	}
+
+	// Client errors are not failures of the host
+	status.Store(http.StatusNotFound)
+	for i := 0; i < 10; i++ {
+		_, _ = client.Get(server.URL)
+	}
+	if got := client.CircuitState(host); got != CircuitClosed {
+		t.Errorf("state = %s after 4xx responses, want closed", got)
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	var status, requests atomic.Int32
+	server, host := switchServer(t, &status, &requests)
+	client := newBreakerClient(CircuitBreakerConfig{FailureRate: 1, MinRequests: 3, Window: 100 * time.Millisecond})
+
+	status.Store(http.StatusServiceUnavailable)
+	_, _ = client.Get(server.URL)
+	_, _ = client.Get(server.URL)
+
+	// The first failures leave the window before the third one
+	time.Sleep(150 * time.Millisecond)
+	_, _ = client.Get(server.URL)
+	if got := client.CircuitState(host); got != CircuitClosed {
+		t.Fatalf("state = %s, want failures outside the window ignored", got)
+	}
+
+	_, _ = client.Get(server.URL)
+	_, _ = client.Get(server.URL)
+	if got := client.CircuitState(host); got != CircuitOpen {
+		t.Errorf("state = %s after 3 failures within the window, want open", got)
+	}
+}
+
+func TestCircuitBreakerPerHost(t *testing.T) {
+	var status1, requests1, status2, requests2 atomic.Int32
+	failing, failingHost := switchServer(t, &status1, &requests1)
+	healthy, healthyHost := switchServer(t, &status2, &requests2)
+	client := newBreakerClient(CircuitBreakerConfig{FailureRate: 1, MinRequests: 2})
+
+	status1.Store(http.StatusInternalServerError)
+	_, _ = client.Get(failing.URL)
+	_, _ = client.Get(failing.URL)
+
+	if got := client.CircuitState(failingHost); got != CircuitOpen {
+		t.Fatalf("failing host state = %s, want open", got)
+	}
+	if _, err := client.Get(healthy.URL); err != nil {
+		t.Errorf("Get() to another host error = %v", err)
+	}
+	if got := client.CircuitState(healthyHost); got != CircuitClosed {
+		t.Errorf("healthy host state = %s, want closed", got)
+	}
+}
+
+func TestCircuitBreakerHalfOpenLimit(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	trial := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		close(trial)
+		<-release
+	}))
+	defer server.Close()
+
+	client := newBreakerClient(CircuitBreakerConfig{FailureRate: 1, MinRequests: 1, Cooldown: 20 * time.Millisecond, HalfOpenRequests: 1})
+	_, _ = client.Get(server.URL)
+	failing.Store(false)
+	time.Sleep(30 * time.Millisecond)
+
+	done := make(chan error)
+	go func() {
+		_, err := client.Get(server.URL)
+		done <- err
+	}()
+
+	select {
+	case <-trial:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the trial request didn't reach the server")
+	}
+	// The single trial request is in flight, so others are rejected
+	if _, err := client.Get(server.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Get() during the trial error = %v, want ErrCircuitOpen", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("trial Get() error = %v", err)
+	}
+	if got := client.CircuitState(serverHost(t, server)); got != CircuitClosed {
+		t.Errorf("state = %s after a successful trial, want closed", got)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	var status, requests atomic.Int32
+	server, host := switchServer(t, &status, &requests)
+	client := NewWithConfig(Config{})
+
+	status.Store(http.StatusInternalServerError)
+	for i := 0; i < 20; i++ {
+		if _, err := client.Get(server.URL); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+	if got := client.CircuitState(host); got != CircuitClosed {
+		t.Errorf("state = %s without a circuit breaker, want closed", got)
+	}
+}
@@ -1,26 +1,67 @@
 package httpclient
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 )
 
 // Client represents an HTTP client with retry and timeout capabilities
 type Client struct {
 	httpClient *http.Client
-	config     Config
+	// streamClient shares the transport of httpClient but has no Timeout,
+	// which would also bound reading a streamed body
+	streamClient *http.Client
+	config       Config
+	doer         Doer
+	// streamDoer is the middleware chain of Stream, leaving bodies open
+	streamDoer Doer
+
+	breakersMu sync.Mutex
+	breakers   map[string]*circuitBreaker
 }
 
 // Config holds HTTP client configuration
 type Config struct {
-	Timeout        time.Duration     `json:"timeout" yaml:"timeout"`
-	Retries        int               `json:"retries" yaml:"retries"`
-	RetryDelay     time.Duration     `json:"retry_delay" yaml:"retry_delay"`
+	// BaseURL is prepended to request URLs that are not absolute, e.g.
+	// "https://api.example.com/v1" turns "/users" into ".../v1/users"
+	BaseURL string        `json:"base_url" yaml:"base_url"`
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+	Retries int           `json:"retries" yaml:"retries"`
+	// RetryDelay is the base of the exponential backoff between retries
+	RetryDelay time.Duration `json:"retry_delay" yaml:"retry_delay"`
+	// MaxRetryDelay caps the backoff and the Retry-After delay honored.
+	// Defaults to 30s.
+	MaxRetryDelay time.Duration `json:"max_retry_delay" yaml:"max_retry_delay"`
+	// RetryStatusCodes are the response status codes that are retried.
+	// Defaults to DefaultRetryStatusCodes.
+	RetryStatusCodes []int `json:"retry_status_codes" yaml:"retry_status_codes"`
+	// RetryPolicy replaces RetryStatusCodes to decide what is retried
+	RetryPolicy RetryPolicy `json:"-" yaml:"-"`
+	// AutoIdempotencyKey gives POST and PATCH requests without an
+	// Idempotency-Key a generated one, kept across retries, so they can be
+	// retried safely
+	AutoIdempotencyKey bool `json:"auto_idempotency_key" yaml:"auto_idempotency_key"`
+	// CircuitBreaker enables a circuit breaker per host when set
+	CircuitBreaker *CircuitBreakerConfig `json:"circuit_breaker" yaml:"circuit_breaker"`
+	// Middleware wraps every attempt, the first one being the outermost
+	Middleware []Middleware `json:"-" yaml:"-"`
+	// ErrorOnStatus returns an *HTTPError instead of the response for 4xx
+	// and 5xx statuses
+	ErrorOnStatus bool `json:"error_on_status" yaml:"error_on_status"`
+	// DisallowUnknownFields makes DoJSON and the other JSON helpers reject
+	// response fields missing from the target type.
+	// WithDisallowUnknownFields overrides it per call.
+	DisallowUnknownFields bool `json:"disallow_unknown_fields" yaml:"disallow_unknown_fields"`
+	// Encoder encodes request bodies other than a Body, JSONEncoder when nil
+	Encoder Encoder `json:"-" yaml:"-"`
+	// Transport is the underlying round tripper, http.DefaultTransport when
+	// nil. Wrap it to act on the wire level, below redirects and timeouts.
+	Transport      http.RoundTripper `json:"-" yaml:"-"`
 	UserAgent      string            `json:"user_agent" yaml:"user_agent"`
 	DefaultHeaders map[string]string `json:"default_headers" yaml:"default_headers"`
 }
@@ -37,81 +78,224 @@ type RequestOption func(*http.Request)
 // New creates a new HTTP client with default configuration
 func New() *Client {
 	return NewWithConfig(Config{
-		Timeout:    30 * time.Second,
-		Retries:    3,
-		RetryDelay: 1 * time.Second,
-		UserAgent:  "gopackkit-httpclient/1.0",
+		Timeout:       30 * time.Second,
+		Retries:       3,
+		RetryDelay:    1 * time.Second,
+		MaxRetryDelay: 30 * time.Second,
+		UserAgent:     "gopackkit-httpclient/1.0",
 	})
 }
 
 // NewWithConfig creates a new HTTP client with custom configuration
 func NewWithConfig(config Config) *Client {
-	return &Client{
+	c := &Client{
 		httpClient: &http.Client{
-			Timeout: config.Timeout,
+			Timeout:   config.Timeout,
+			Transport: config.Transport,
+		},
+		streamClient: &http.Client{
+			Transport: config.Transport,
 		},
 		config: config,
 	}
+	c.doer = chain(config.Middleware, DoerFunc(c.doRequest))
+	c.streamDoer = chain(config.Middleware, DoerFunc(c.streamRequest))
+	return c
 }
 
 // Get performs a GET request
 func (c *Client) Get(url string, opts ...RequestOption) (*Response, error) {
-	return c.Do(http.MethodGet, url, nil, opts...)
+	return c.GetCtx(context.Background(), url, opts...)
 }
 
 // Post performs a POST request
 func (c *Client) Post(url string, body interface{}, opts ...RequestOption) (*Response, error) {
-	return c.Do(http.MethodPost, url, body, opts...)
+	return c.PostCtx(context.Background(), url, body, opts...)
 }
 
 // Put performs a PUT request
 func (c *Client) Put(url string, body interface{}, opts ...RequestOption) (*Response, error) {
-	return c.Do(http.MethodPut, url, body, opts...)
+	return c.PutCtx(context.Background(), url, body, opts...)
 }
 
 // Delete performs a DELETE request
 func (c *Client) Delete(url string, opts ...RequestOption) (*Response, error) {
-	return c.Do(http.MethodDelete, url, nil, opts...)
+	return c.DeleteCtx(context.Background(), url, opts...)
 }
 
 // Do performs an HTTP request with retry logic
 func (c *Client) Do(method, url string, body interface{}, opts ...RequestOption) (*Response, error) {
-	var lastErr error
+	return c.DoCtx(context.Background(), method, url, body, opts...)
+}
+
+// GetCtx performs a GET request bound to ctx
+func (c *Client) GetCtx(ctx context.Context, url string, opts ...RequestOption) (*Response, error) {
+	return c.DoCtx(ctx, http.MethodGet, url, nil, opts...)
+}
+
+// PostCtx performs a POST request bound to ctx
+func (c *Client) PostCtx(ctx context.Context, url string, body interface{}, opts ...RequestOption) (*Response, error) {
+	return c.DoCtx(ctx, http.MethodPost, url, body, opts...)
+}
+
+// PutCtx performs a PUT request bound to ctx
+func (c *Client) PutCtx(ctx context.Context, url string, body interface{}, opts ...RequestOption) (*Response, error) {
+	return c.DoCtx(ctx, http.MethodPut, url, body, opts...)
+}
+
+// DeleteCtx performs a DELETE request bound to ctx
+func (c *Client) DeleteCtx(ctx context.Context, url string, opts ...RequestOption) (*Response, error) {
+	return c.DoCtx(ctx, http.MethodDelete, url, nil, opts...)
+}
+
+// DoCtx performs an HTTP request with retry logic. ctx governs every
+// attempt and the delay between them: once it is canceled or its deadline
+// passes, DoCtx stops retrying and returns an error wrapping ctx.Err().
+//
+// Transport errors and responses matching the retry policy are retried with
+// exponential backoff, honoring Retry-After. When retries are exhausted the
+// last response is returned as is, or as an *HTTPError for a 4xx or 5xx
+// status with ErrorOnStatus. POST, PATCH and other non-idempotent requests
+// are only retried when they carry an Idempotency-Key.
+func (c *Client) DoCtx(ctx context.Context, method, url string, body interface{}, opts ...RequestOption) (*Response, error) {
+	return c.do(ctx, method, url, body, false, opts...)
+}
+
+// do implements DoCtx and Stream. With stream set, response bodies are left
+// open for the caller instead of being read into Response.Body.
+func (c *Client) do(ctx context.Context, method, url string, body interface{}, stream bool, opts ...RequestOption) (*Response, error) {
+	doer := c.doer
+	if stream {
+		doer = c.streamDoer
+	}
 
-	for attempt := 0; attempt <= c.config.Retries; attempt++ {
-		resp, err := c.doRequest(method, url, body, opts...)
-		if err == nil {
-			return resp, nil
+	payload, err := c.prepareBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// One key per logical request, so the server sees retries as the same request
+	idempotencyKey := ""
+	if c.config.AutoIdempotencyKey && !idempotentMethod(method) {
+		idempotencyKey = newIdempotencyKey()
+	}
+
+	retry := c.retryPolicy()
+
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("request canceled: %w", err)
 		}
 
-		lastErr = err
+		// The request and its body reader are rebuilt for every attempt
+		req, err := c.newRequest(ctx, method, url, payload, idempotencyKey, opts...)
+		if err != nil {
+			return nil, err
+		}
 
-		// Don't retry on the last attempt
-		if attempt < c.config.Retries {
-			time.Sleep(c.config.RetryDelay)
+		breaker := c.breaker(req.URL.Host)
+		var generation uint64
+		if breaker != nil {
+			generation, err = breaker.allow()
+			if err != nil {
+				if req.Body != nil {
+					_ = req.Body.Close()
+				}
+				return nil, fmt.Errorf("request to %s rejected: %w", req.URL.Host, err)
+			}
+		}
+
+		resp, err := doer.Do(req)
+
+		// A failure caused by the context, or by one set with WithContext, is
+		// not worth retrying
+		if err != nil {
+			if ctxErr := contextErr(ctx, req); ctxErr != nil {
+				if breaker != nil {
+					breaker.release(generation)
+				}
+				return nil, fmt.Errorf("request canceled: %w", ctxErr)
+			}
 		}
-	}
 
-	return nil, fmt.Errorf("request failed after %d attempts: %w", c.config.Retries+1, lastErr)
+		if breaker != nil {
+			breaker.record(generation, resp, err)
+		}
+
+		if attempt < c.config.Retries && retryable(req) && payload.canReplay() && retry(resp, err) {
+			delay, ok := c.retryDelay(attempt, resp)
+			if ok {
+				// A streamed body is still open and must be released first
+				if resp != nil && stream {
+					_ = resp.Response.Body.Close()
+				}
+				if err := sleepCtx(ctx, delay); err != nil {
+					return nil, fmt.Errorf("request canceled: %w", err)
+				}
+				continue
+			}
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("request failed after %d attempts: %w", attempt+1, err)
+		}
+		if err := c.statusError(resp, stream); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
 }
 
-// doRequest performs a single HTTP request
-func (c *Client) doRequest(method, url string, body interface{}, opts ...RequestOption) (*Response, error) {
-	var reqBody io.Reader
+// newRequest builds the request of a single attempt
+func (c *Client) newRequest(ctx context.Context, method, url string, payload *Body, idempotencyKey string, opts ...RequestOption) (*http.Request, error) {
+	url, err := c.resolveURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
 
-	if body != nil {
-		jsonBody, err := json.Marshal(body)
+	var reqBody io.Reader
+	if payload != nil {
+		r, err := payload.reader()
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+			return nil, err
 		}
-		reqBody = bytes.NewBuffer(jsonBody)
+		reqBody = r
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), method, url, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
+		if closer, ok := reqBody.(io.Closer); ok {
+			_ = closer.Close()
+		}
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	// Streamed bodies are hidden behind a plain io.Reader, which
+	// NewRequestWithContext can't take the length of
+	if payload != nil && payload.length != nil {
+		switch n := payload.length(); {
+		case n == 0:
+			req.Body = http.NoBody
+			req.ContentLength = 0
+		case n > 0:
+			req.ContentLength = n
+		}
+	}
+
+	// Let redirects resend bodies that can be read again
+	if payload != nil && payload.open != nil && payload.replayable {
+		req.GetBody = func() (io.ReadCloser, error) {
+			r, err := payload.reader()
+			if err != nil {
+				return nil, err
+			}
+			if rc, ok := r.(io.ReadCloser); ok {
+				return rc, nil
+			}
+			return io.NopCloser(r), nil
+		}
+	}
+
 	// Set default headers
 	for key, value := range c.config.DefaultHeaders {
 		req.Header.Set(key, value)
@@ -123,8 +307,13 @@ func (c *Client) doRequest(method, url string, body interface{}, opts ...Request
 	}
 
 	// Set Content-Type for requests with body
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
+	if payload != nil && payload.contentType != "" {
+		req.Header.Set("Content-Type", payload.contentType)
+	}
+
+	// Set the generated idempotency key unless one was configured
+	if idempotencyKey != "" && req.Header.Get(IdempotencyKeyHeader) == "" {
+		req.Header.Set(IdempotencyKeyHeader, idempotencyKey)
 	}
 
 	// Apply request options
@@ -132,17 +321,23 @@ func (c *Client) doRequest(method, url string, body interface{}, opts ...Request
 		opt(req)
 	}
 
+	return req, nil
+}
+
+// doRequest performs a single HTTP request
+func (c *Client) doRequest(req *http.Request) (*Response, error) {
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
+
 	defer func() {
 		if closeErr := resp.Body.Close(); closeErr != nil {
 			// Log the error or handle as appropriate
 			_ = closeErr
 		}
 	}()
-	
+
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
@@ -154,6 +349,42 @@ func (c *Client) doRequest(method, url string, body interface{}, opts ...Request
 	}, nil
 }
 
+// streamRequest performs a single HTTP request, leaving the body open for
+// the caller
+func (c *Client) streamRequest(req *http.Request) (*Response, error) {
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	return &Response{Response: resp}, nil
+}
+
+// contextErr returns the error of ctx or, when WithContext replaced it, of
+// the context of req
+func contextErr(ctx context.Context, req *http.Request) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return req.Context().Err()
+}
+
+// sleepCtx waits for d or until ctx is done, whichever comes first
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 // WithHeader adds a header to the request
 func WithHeader(key, value string) RequestOption {
 	return func(req *http.Request) {
@@ -170,7 +401,12 @@ func WithHeaders(headers map[string]string) RequestOption {
 	}
 }
 
-// WithContext adds a context to the request
+// WithContext adds a context to the request. It is set on every attempt, and
+// once it is done the request is not retried. The delay between retries
+// doesn't watch it, so a cancellation is only noticed when the next attempt
+// starts.
+//
+// Deprecated: use the Ctx methods, such as GetCtx and DoCtx, instead.
 func WithContext(ctx context.Context) RequestOption {
 	return func(req *http.Request) {
 		*req = *req.WithContext(ctx)
@@ -0,0 +1,90 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countAttempts is a middleware counting the attempts made by a client
+func countAttempts(n *atomic.Int32) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*Response, error) {
+			n.Add(1)
+			return next.Do(req)
+		})
+	}
+}
+
+// statusServer answers every request with status and counts them
+func statusServer(t *testing.T, status int, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWithContextStopsRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := statusServer(t, http.StatusServiceUnavailable, &requests)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The context is canceled once the first attempt got its response
+	var attempts atomic.Int32
+	cancelAfterFirst := func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*Response, error) {
+			resp, err := next.Do(req)
+			cancel()
+			return resp, err
+		})
+	}
+	client := NewWithConfig(Config{
+		Retries:    5,
+		RetryDelay: time.Millisecond,
+		Middleware: []Middleware{countAttempts(&attempts), cancelAfterFirst},
+	})
+
+	_, err := client.Get(server.URL, WithContext(ctx))
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "request canceled") {
+		t.Fatalf("Get() error = %v, want the request canceled", err)
+	}
+	// The attempt after the cancellation fails right away and ends the request
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("made %d attempts, want 2", got)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server got %d requests, want 1", got)
+	}
+}
+
+func TestDoCtxCanceledDuringDelay(t *testing.T) {
+	var requests atomic.Int32
+	server := statusServer(t, http.StatusServiceUnavailable, &requests)
+
+	client := NewWithConfig(Config{Retries: 3, RetryDelay: time.Hour, MaxRetryDelay: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetCtx(ctx, server.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetCtx() error = %v, want the deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("GetCtx() returned after %s, want the delay cut short", elapsed)
+	}
+	if got := requests.Load(); got > 2 {
+		t.Errorf("server got %d requests", got)
+	}
+}
@@ -0,0 +1,156 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// maxErrorBodySize bounds the response body kept in an HTTPError
+const maxErrorBodySize = 4 << 10
+
+var (
+	// ErrNotFound matches HTTP errors with status 404
+	ErrNotFound = errors.New("not found")
+	// ErrConflict matches HTTP errors with status 409
+	ErrConflict = errors.New("conflict")
+	// ErrTooManyRequests matches HTTP errors with status 429
+	ErrTooManyRequests = errors.New("too many requests")
+	// ErrServerError matches HTTP errors with a 5xx status
+	ErrServerError = errors.New("server error")
+)
+
+// HTTPError is returned for responses with a 4xx or 5xx status when
+// Config.ErrorOnStatus is set, by Response.Err, and by DoJSON for any status
+// other than 2xx
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Header     http.Header
+	// Body holds the start of the response body, up to 4 KiB
+	Body []byte
+	// Problem is set when the body is an RFC 7807 application/problem+json
+	Problem *Problem
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%s %s: %s", e.Method, e.URL, e.Status)
+	if e.Problem != nil {
+		switch {
+		case e.Problem.Title != "" && e.Problem.Detail != "":
+			msg += ": " + e.Problem.Title + ": " + e.Problem.Detail
+		case e.Problem.Detail != "":
+			msg += ": " + e.Problem.Detail
+		case e.Problem.Title != "":
+			msg += ": " + e.Problem.Title
+		}
+	}
+	return msg
+}
+
+// Is lets errors.Is match ErrNotFound, ErrConflict, ErrTooManyRequests and
+// ErrServerError
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrTooManyRequests:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServerError:
+		return e.StatusCode >= http.StatusInternalServerError && e.StatusCode < 600
+	default:
+		return false
+	}
+}
+
+// JSON unmarshals the error body into v, for APIs with their own error format
+func (e *HTTPError) JSON(v interface{}) error {
+	return json.Unmarshal(e.Body, v)
+}
+
+// Problem is an RFC 7807 problem details object
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Extensions holds the members not defined by RFC 7807
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// UnmarshalJSON decodes a problem, collecting extension members
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	type problem Problem
+	if err := json.Unmarshal(data, (*problem)(p)); err != nil {
+		return err
+	}
+
+	var members map[string]interface{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	for _, name := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, name)
+	}
+	if len(members) > 0 {
+		p.Extensions = members
+	}
+	return nil
+}
+
+// Err returns an *HTTPError when the response has a 4xx or 5xx status, or nil
+func (r *Response) Err() error {
+	if r.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	return newHTTPError(r.Response, r.Body)
+}
+
+// newHTTPError builds the error of a failed response from its body
+func newHTTPError(resp *http.Response, body []byte) *HTTPError {
+	e := &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+	}
+	if resp.Request != nil {
+		e.Method = resp.Request.Method
+		e.URL = resp.Request.URL.Redacted()
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && mediaType == "application/problem+json" {
+		var problem Problem
+		if err := json.Unmarshal(body, &problem); err == nil {
+			e.Problem = &problem
+		}
+	}
+
+	if len(body) > maxErrorBodySize {
+		body = body[:maxErrorBodySize]
+	}
+	e.Body = body
+	return e
+}
+
+// statusError returns the error of resp when ErrorOnStatus is set, closing a
+// streamed body after reading the start of it
+func (c *Client) statusError(resp *Response, stream bool) error {
+	if !c.config.ErrorOnStatus || resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	body := resp.Body
+	if stream {
+		body, _ = io.ReadAll(io.LimitReader(resp.Response.Body, maxErrorBodySize))
+		_ = resp.Response.Body.Close()
+	}
+	return newHTTPError(resp.Response, body)
+}
@@ -0,0 +1,42 @@
+package httpclient
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKeyHeader is the header carrying the idempotency key that lets
+// a server recognize retries of the same request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// WithIdempotencyKey sets the idempotency key of the request, allowing
+// POST and PATCH requests to be retried
+func WithIdempotencyKey(key string) RequestOption {
+	return func(req *http.Request) {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+}
+
+// idempotentMethod reports whether repeating a request with method has the
+// same effect as sending it once (RFC 9110 section 9.2.2)
+func idempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryable reports whether req may be sent again: idempotent methods always
+// can, others such as POST and PATCH only with an idempotency key
+func retryable(req *http.Request) bool {
+	return idempotentMethod(req.Method) || req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// newIdempotencyKey returns a random key for one logical request
+func newIdempotencyKey() string {
+	return uuid.NewString()
+}
@@ -0,0 +1,114 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// JSONOption customizes a call to DoJSON or one of the other JSON helpers.
+// Every RequestOption is a JSONOption, so request options can be passed
+// along with the JSON specific ones.
+type JSONOption interface {
+	applyJSON(*jsonConfig)
+}
+
+type jsonConfig struct {
+	disallowUnknownFields bool
+	opts                  []RequestOption
+}
+
+type jsonOptionFunc func(*jsonConfig)
+
+func (f jsonOptionFunc) applyJSON(c *jsonConfig) {
+	f(c)
+}
+
+func (o RequestOption) applyJSON(c *jsonConfig) {
+	c.opts = append(c.opts, o)
+}
+
+// WithDisallowUnknownFields rejects (or, with disallow false, accepts)
+// response fields missing from the target type, overriding
+// Config.DisallowUnknownFields for that call
+func WithDisallowUnknownFields(disallow bool) JSONOption {
+	return jsonOptionFunc(func(c *jsonConfig) {
+		c.disallowUnknownFields = disallow
+	})
+}
+
+// GetJSON performs a GET request and decodes the JSON response into a T
+func GetJSON[T any](ctx context.Context, c *Client, url string, opts ...JSONOption) (T, error) {
+	return DoJSON[T](ctx, c, http.MethodGet, url, nil, opts...)
+}
+
+// PostJSON performs a POST request with body and decodes the JSON response
+// into a Resp
+func PostJSON[Resp, Req any](ctx context.Context, c *Client, url string, body Req, opts ...JSONOption) (Resp, error) {
+	return DoJSON[Resp](ctx, c, http.MethodPost, url, body, opts...)
+}
+
+// PutJSON performs a PUT request with body and decodes the JSON response
+// into a Resp
+func PutJSON[Resp, Req any](ctx context.Context, c *Client, url string, body Req, opts ...JSONOption) (Resp, error) {
+	return DoJSON[Resp](ctx, c, http.MethodPut, url, body, opts...)
+}
+
+// PatchJSON performs a PATCH request with body and decodes the JSON response
+// into a Resp
+func PatchJSON[Resp, Req any](ctx context.Context, c *Client, url string, body Req, opts ...JSONOption) (Resp, error) {
+	return DoJSON[Resp](ctx, c, http.MethodPatch, url, body, opts...)
+}
+
+// DeleteJSON performs a DELETE request and decodes the JSON response into a T
+func DeleteJSON[T any](ctx context.Context, c *Client, url string, opts ...JSONOption) (T, error) {
+	return DoJSON[T](ctx, c, http.MethodDelete, url, nil, opts...)
+}
+
+// DoJSON performs a request like DoCtx and decodes the JSON response into a
+// T, streaming it from the connection instead of buffering the body. A
+// status other than 2xx is returned as an *HTTPError, and an empty body,
+// e.g. 204 No Content, leaves the result at its zero value. Unknown fields
+// are rejected when Config.DisallowUnknownFields is set, unless the call
+// says otherwise, see WithDisallowUnknownFields. On error the zero value is
+// returned, never a partially decoded one.
+func DoJSON[T any](ctx context.Context, c *Client, method, url string, body interface{}, opts ...JSONOption) (T, error) {
+	var result T
+
+	// Accept comes first so callers can still override it
+	config := jsonConfig{
+		disallowUnknownFields: c.config.DisallowUnknownFields,
+		opts:                  []RequestOption{WithHeader("Accept", "application/json")},
+	}
+	for _, opt := range opts {
+		opt.applyJSON(&config)
+	}
+
+	resp, err := c.Stream(ctx, method, url, body, config.opts...)
+	if err != nil {
+		return result, err
+	}
+	defer func() {
+		// Drain what's left after the JSON value so the connection can be reused
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return result, newHTTPError(resp, data)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	if config.disallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&result); err != nil && !errors.Is(err, io.EOF) {
+		var zero T
+		return zero, fmt.Errorf("failed to decode response body: %w", err)
+	}
+	return result, nil
+}
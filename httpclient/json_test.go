@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type jsonUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// userServer echoes the decoded request body back with an extra field the
+// client's type doesn't know about
+func userServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := map[string]interface{}{"id": 1, "name": "jane", "role": "admin"}
+		if r.Body != nil {
+			_ = json.NewDecoder(r.Body).Decode(&user)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(user)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPostJSONInfersRequestType(t *testing.T) {
+	server := userServer(t)
+	client := NewWithConfig(Config{BaseURL: server.URL})
+
+	user, err := PostJSON[jsonUser](context.Background(), client, "/users", map[string]string{"name": "john"})
+	if err != nil {
+		t.Fatalf("PostJSON() error = %v", err)
+	}
+	if user.Name != "john" {
+		t.Errorf("PostJSON() = %+v, want the echoed name", user)
+	}
+}
+
+func TestWithDisallowUnknownFields(t *testing.T) {
+	server := userServer(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		config bool
+		opts   []JSONOption
+		strict bool
+	}{
+		{name: "lenient by default"},
+		{name: "strict client", config: true, strict: true},
+		{name: "strict call", opts: []JSONOption{WithDisallowUnknownFields(true)}, strict: true},
+		{name: "lenient call on a strict client", config: true, opts: []JSONOption{WithDisallowUnknownFields(false)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewWithConfig(Config{BaseURL: server.URL, DisallowUnknownFields: tt.config})
+
+			user, err := GetJSON[jsonUser](ctx, client, "/users/1", tt.opts...)
+			if tt.strict {
+				if err == nil {
+					t.Fatal("GetJSON() accepted an unknown field")
+				}
+				// Never a partially decoded value
+				if user != (jsonUser{}) {
+					t.Errorf("GetJSON() = %+v, want the zero value", user)
+				}
+				return
+			}
+			if err != nil || user.Name != "jane" {
+				t.Errorf("GetJSON() = %+v, %v", user, err)
+			}
+		})
+	}
+}
+
+func TestJSONOptionsAcceptRequestOptions(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewWithConfig(Config{})
+	_, err := GetJSON[jsonUser](context.Background(), client, server.URL,
+		WithHeader("Accept", "application/vnd.api+json"),
+		WithBearerToken("secret"),
+		WithDisallowUnknownFields(true))
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("GetJSON() error = %v, want an *HTTPError for 404", err)
+	}
+	if header.Get("Accept") != "application/vnd.api+json" || header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("request headers = %v, want the request options applied", header)
+	}
+}
@@ -0,0 +1,39 @@
+package httpclient
+
+import "net/http"
+
+// Doer sends a single HTTP request attempt
+type Doer interface {
+	Do(req *http.Request) (*Response, error)
+}
+
+// DoerFunc adapts a function to a Doer
+type DoerFunc func(req *http.Request) (*Response, error)
+
+// Do calls f(req)
+func (f DoerFunc) Do(req *http.Request) (*Response, error) {
+	return f(req)
+}
+
+// Middleware wraps a Doer to add behavior around every attempt, such as
+// authentication, logging, metrics, tracing or caching. It may change the
+// request, inspect or replace the response, or answer without calling next.
+type Middleware func(next Doer) Doer
+
+// RoundTripperFunc adapts a function to an http.RoundTripper, which is handy
+// to wrap Config.Transport
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// chain wraps final with middleware so that the first one runs first
+func chain(middleware []Middleware, final Doer) Doer {
+	doer := final
+	for i := len(middleware) - 1; i >= 0; i-- {
+		doer = middleware[i](doer)
+	}
+	return doer
+}
@@ -0,0 +1,119 @@
+package httpclient
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMaxRetryDelay caps the backoff when Config.MaxRetryDelay is unset
+const defaultMaxRetryDelay = 30 * time.Second
+
+// DefaultRetryStatusCodes are the response status codes retried when
+// Config.RetryStatusCodes is unset
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy decides whether a request is retried after an attempt. resp is
+// nil when the attempt failed with err.
+type RetryPolicy func(resp *Response, err error) bool
+
+// StatusRetryPolicy returns a RetryPolicy retrying transport errors and
+// responses with one of the given status codes
+func StatusRetryPolicy(codes ...int) RetryPolicy {
+	retryable := make(map[int]bool, len(codes))
+	for _, code := range codes {
+		retryable[code] = true
+	}
+
+	return func(resp *Response, err error) bool {
+		if err != nil {
+			return true
+		}
+		return resp != nil && retryable[resp.StatusCode]
+	}
+}
+
+// retryPolicy returns the configured policy, or one retrying the configured
+// status codes
+func (c *Client) retryPolicy() RetryPolicy {
+	if c.config.RetryPolicy != nil {
+		return c.config.RetryPolicy
+	}
+	if c.config.RetryStatusCodes != nil {
+		return StatusRetryPolicy(c.config.RetryStatusCodes...)
+	}
+	return StatusRetryPolicy(DefaultRetryStatusCodes...)
+}
+
+// retryDelay returns how long to wait before the retry following attempt
+// (0-based). A Retry-After header on resp takes precedence over the backoff;
+// ok is false when it asks for longer than MaxRetryDelay, in which case the
+// request should not be retried.
+func (c *Client) retryDelay(attempt int, resp *Response) (delay time.Duration, ok bool) {
+	maxDelay := c.config.MaxRetryDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxRetryDelay
+	}
+
+	if resp != nil {
+		if after, found := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); found {
+			if after > maxDelay {
+				return 0, false
+			}
+			return after, true
+		}
+	}
+
+	return backoff(c.config.RetryDelay, maxDelay, attempt), true
+}
+
+// backoff returns an exponential backoff with full jitter: a random delay
+// between zero and base * 2^attempt, capped at maxDelay, so many clients
+// don't retry in lockstep
+func backoff(base, maxDelay time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	ceiling := base
+	for i := 0; i < attempt && ceiling < maxDelay; i++ {
+		ceiling *= 2
+	}
+	if ceiling > maxDelay {
+		ceiling = maxDelay
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as
+// an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := at.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
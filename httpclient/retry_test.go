@@ -0,0 +1,291 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoffJitterBounds(t *testing.T) {
+	base, maxDelay := 100*time.Millisecond, time.Second
+
+	for attempt, ceiling := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < 200; i++ {
+			delay := backoff(base, maxDelay, attempt)
+			if delay < 0 || delay > ceiling {
+				t.Fatalf("backoff(attempt %d) = %s, want within [0, %s]", attempt, delay, ceiling)
+			}
+			seen[delay] = true
+		}
+		// Full jitter spreads the delays instead of using the ceiling
+		if len(seen) < 10 {
+			t.Errorf("backoff(attempt %d) gave only %d distinct delays", attempt, len(seen))
+		}
+	}
+
+	if got := backoff(0, maxDelay, 3); got != 0 {
+		t.Errorf("backoff without a base = %s, want 0", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 8, 9, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{" 0 ", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{"Sat, 09 Aug 2025 10:00:30 GMT", 30 * time.Second, true},
+		// A date in the past means now
+		{"Sat, 09 Aug 2025 09:00:00 GMT", 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// flakyServer fails the first n requests with status and a Retry-After
+// header, then answers 200
+func flakyServer(t *testing.T, n int32, status int, retryAfter string, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= n {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRetryStatusCodes(t *testing.T) {
+	tests := []struct {
+		status   int
+		retried  bool
+		codes    []int
+		wantCode int
+	}{
+		{http.StatusServiceUnavailable, true, nil, http.StatusOK},
+		{http.StatusTooManyRequests, true, nil, http.StatusOK},
+		{http.StatusInternalServerError, false, nil, http.StatusInternalServerError},
+		{http.StatusNotFound, false, nil, http.StatusNotFound},
+		{http.StatusInternalServerError, true, []int{http.StatusInternalServerError}, http.StatusOK},
+		{http.StatusServiceUnavailable, false, []int{}, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			var requests atomic.Int32
+			server := flakyServer(t, 1, tt.status, "", &requests)
+			client := NewWithConfig(Config{Retries: 2, RetryDelay: time.Millisecond, RetryStatusCodes: tt.codes})
+
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			want := int32(1)
+			if tt.retried {
+				want = 2
+			}
+			if got := requests.Load(); got != want {
+				t.Errorf("server got %d requests, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	var requests atomic.Int32
+	server := flakyServer(t, 100, http.StatusBadGateway, "", &requests)
+	client := NewWithConfig(Config{Retries: 2, RetryDelay: time.Millisecond})
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	// The last response is returned as is
+	if resp.StatusCode != http.StatusBadGateway || requests.Load() != 3 {
+		t.Errorf("status = %d after %d requests, want 502 after 3", resp.StatusCode, requests.Load())
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	var requests atomic.Int32
+	server := flakyServer(t, 1, http.StatusConflict, "", &requests)
+
+	var policyCalls int
+	client := NewWithConfig(Config{
+		Retries:    3,
+		RetryDelay: time.Millisecond,
+		RetryPolicy: func(resp *Response, err error) bool {
+			policyCalls++
+			return err == nil && resp.StatusCode == http.StatusConflict
+		},
+	})
+
+	resp, err := client.Get(server.URL)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Get() = %v, %v, want 200 after a retry", resp, err)
+	}
+	// Consulted after every attempt, including the successful one
+	if policyCalls != 2 || requests.Load() != 2 {
+		t.Errorf("policy called %d times for %d requests", policyCalls, requests.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Run("honored", func(t *testing.T) {
+		var requests atomic.Int32
+		server := flakyServer(t, 1, http.StatusTooManyRequests, "1", &requests)
+		// The backoff alone would retry right away
+		client := NewWithConfig(Config{Retries: 1, RetryDelay: time.Nanosecond})
+
+		start := time.Now()
+		resp, err := client.Get(server.URL)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("Get() = %v, %v, want 200", resp, err)
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("retried after %s, want the 1s of Retry-After", elapsed)
+		}
+	})
+
+	t.Run("longer than MaxRetryDelay", func(t *testing.T) {
+		var requests atomic.Int32
+		server := flakyServer(t, 1, http.StatusServiceUnavailable, "120", &requests)
+		client := NewWithConfig(Config{Retries: 3, RetryDelay: time.Millisecond, MaxRetryDelay: time.Second})
+
+		start := time.Now()
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		// Not retried: the server asked for longer than the client waits
+		if resp.StatusCode != http.StatusServiceUnavailable || requests.Load() != 1 {
+			t.Errorf("status = %d after %d requests, want 503 after 1", resp.StatusCode, requests.Load())
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("Get() took %s, want no wait", elapsed)
+		}
+	})
+}
+
+func TestRetryIdempotency(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		auto    bool
+		opts    []RequestOption
+		retried bool
+	}{
+		{name: "GET", method: http.MethodGet, retried: true},
+		{name: "PUT", method: http.MethodPut, retried: true},
+		{name: "DELETE", method: http.MethodDelete, retried: true},
+		{name: "POST", method: http.MethodPost, retried: false},
+		{name: "PATCH", method: http.MethodPatch, retried: false},
+		{name: "POST with key", method: http.MethodPost, opts: []RequestOption{WithIdempotencyKey("k1")}, retried: true},
+		{name: "POST with auto key", method: http.MethodPost, auto: true, retried: true},
+		{name: "PATCH with auto key", method: http.MethodPatch, auto: true, retried: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			var keys []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+				if requests.Add(1) == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+
+			client := NewWithConfig(Config{Retries: 2, RetryDelay: time.Millisecond, AutoIdempotencyKey: tt.auto})
+			if _, err := client.Do(tt.method, server.URL, map[string]string{"a": "b"}, tt.opts...); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+
+			want := int32(1)
+			if tt.retried {
+				want = 2
+			}
+			if got := requests.Load(); got != want {
+				t.Fatalf("server got %d requests, want %d", got, want)
+			}
+			// Retries carry the same key, so the server can recognize them
+			if len(keys) == 2 && keys[0] != keys[1] {
+				t.Errorf("idempotency keys = %v, want the same key on every attempt", keys)
+			}
+			if tt.auto && keys[0] == "" {
+				t.Error("no idempotency key was generated")
+			}
+			if tt.name == "POST with key" && keys[0] != "k1" {
+				t.Errorf("idempotency key = %q, want k1", keys[0])
+			}
+		})
+	}
+}
+
+func TestRetryReplaysBody(t *testing.T) {
+	var requests atomic.Int32
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := NewWithConfig(Config{Retries: 1, RetryDelay: time.Millisecond})
+	if _, err := client.Put(server.URL, map[string]string{"name": "jane"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || !strings.Contains(bodies[1], "jane") {
+		t.Errorf("bodies = %q, want the same body on both attempts", bodies)
+	}
+}
+
+func TestRetryTransportError(t *testing.T) {
+	var attempts atomic.Int32
+	failing := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection reset")
+	})
+	client := NewWithConfig(Config{
+		Retries:    2,
+		RetryDelay: time.Millisecond,
+		Transport:  failing,
+		Middleware: []Middleware{countAttempts(&attempts)},
+	})
+
+	_, err := client.Get("http://example.invalid")
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("Get() error = %v, want a failure after 3 attempts", err)
+	}
+	if attempts.Load() != 3 {
+		t.Errorf("made %d attempts, want 3", attempts.Load())
+	}
+}
@@ -0,0 +1,313 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrChecksumMismatch is returned by Download when the downloaded file does
+// not match the expected checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// errRangeNotSatisfiable means the partial download can't be resumed
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// Stream performs an HTTP request like DoCtx but returns the live response
+// instead of reading the body into memory. The caller must close the
+// response body. Middleware sees a Response whose Body field is nil.
+//
+// Config.Timeout doesn't apply, since it would also bound reading the body
+// and cut large responses short. Bound the request with ctx instead.
+func (c *Client) Stream(ctx context.Context, method, url string, body interface{}, opts ...RequestOption) (*http.Response, error) {
+	resp, err := c.do(ctx, method, url, body, true, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Response, nil
+}
+
+// ProgressFunc reports download progress. total is -1 when the size is unknown.
+type ProgressFunc func(written, total int64)
+
+// DownloadOption customizes a download
+type DownloadOption func(*downloadConfig)
+
+type downloadConfig struct {
+	progress ProgressFunc
+	newHash  func() hash.Hash
+	checksum string
+	opts     []RequestOption
+}
+
+// WithProgress calls fn as the download progresses
+func WithProgress(fn ProgressFunc) DownloadOption {
+	return func(c *downloadConfig) {
+		c.progress = fn
+	}
+}
+
+// WithChecksum verifies the downloaded file against a hex encoded digest.
+// newHash defaults to sha256.New when nil.
+func WithChecksum(newHash func() hash.Hash, checksum string) DownloadOption {
+	return func(c *downloadConfig) {
+		if newHash == nil {
+			newHash = sha256.New
+		}
+		c.newHash = newHash
+		c.checksum = strings.ToLower(strings.TrimSpace(checksum))
+	}
+}
+
+// WithRequestOptions applies request options to the download request
+func WithRequestOptions(opts ...RequestOption) DownloadOption {
+	return func(c *downloadConfig) {
+		c.opts = append(c.opts, opts...)
+	}
+}
+
+// Download streams url to the file at path. Data is written to path+".part"
+// first and renamed once complete and verified, so path never holds a
+// partial file. Like Stream, it is bounded by ctx rather than Config.Timeout.
+//
+// When an earlier download was interrupted, it resumes from the partial file
+// with a Range request. The ETag or Last-Modified of the first response is
+// kept in path+".part.meta" and sent as If-Range, so the server sends the
+// whole file again if it changed in the meantime. Without such a validator,
+// or when the server doesn't support ranges, the download starts over.
+func (c *Client) Download(ctx context.Context, url, path string, opts ...DownloadOption) error {
+	var config downloadConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	partPath := path + ".part"
+	err := c.download(ctx, url, partPath, config)
+	if errors.Is(err, errRangeNotSatisfiable) {
+		// The partial file doesn't match the remote one, start over
+		if err := os.Remove(partPath); err != nil {
+			return fmt.Errorf("failed to remove partial download: %w", err)
+		}
+		_ = os.Remove(metaPath(partPath))
+		err = c.download(ctx, url, partPath, config)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(partPath, path); err != nil {
+		return fmt.Errorf("failed to move download into place: %w", err)
+	}
+	_ = os.Remove(metaPath(partPath))
+	return nil
+}
+
+// download fetches url into partPath, appending to it when it exists
+func (c *Client) download(ctx context.Context, url, partPath string, config downloadConfig) error {
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open download file: %w", err)
+	}
+	defer file.Close()
+
+	// Continue after what was downloaded before, hashing it when verifying
+	var hasher hash.Hash
+	var offset int64
+	if config.newHash != nil {
+		hasher = config.newHash()
+		offset, err = io.Copy(hasher, file)
+	} else {
+		offset, err = file.Seek(0, io.SeekEnd)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read partial download: %w", err)
+	}
+
+	// restart discards the partial file
+	restart := func() error {
+		offset = 0
+		if err := file.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate download file: %w", err)
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to truncate download file: %w", err)
+		}
+		if hasher != nil {
+			hasher.Reset()
+		}
+		return nil
+	}
+
+	reqOpts := config.opts
+	if offset > 0 {
+		// Without a validator there is no telling whether the partial file
+		// still matches the remote one
+		validator := readDownloadMeta(partPath).validator()
+		if validator == "" {
+			if err := restart(); err != nil {
+				return err
+			}
+		} else {
+			reqOpts = append(reqOpts[:len(reqOpts):len(reqOpts)],
+				WithHeader("Range", fmt.Sprintf("bytes=%d-", offset)),
+				WithHeader("If-Range", validator))
+		}
+	}
+
+	resp, err := c.Stream(ctx, http.MethodGet, url, nil, reqOpts...)
+	if err != nil {
+		var httpErr *HTTPError
+		if offset > 0 && errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			return errRangeNotSatisfiable
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+			return fmt.Errorf("failed to resume download: unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+		}
+	case resp.StatusCode == http.StatusOK:
+		// A new download, or the server ignored the range or sent the whole
+		// file because it changed
+		if err := restart(); err != nil {
+			return err
+		}
+		if err := writeDownloadMeta(partPath, resp.Header); err != nil {
+			return err
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		return errRangeNotSatisfiable
+	default:
+		return fmt.Errorf("download failed: unexpected status %s", resp.Status)
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+
+	var dst io.Writer = file
+	if hasher != nil {
+		dst = io.MultiWriter(file, hasher)
+	}
+	if config.progress != nil {
+		config.progress(offset, total)
+		dst = &progressWriter{w: dst, written: offset, total: total, fn: config.progress}
+	}
+
+	// The partial file is kept on failure so a later call can resume
+	if _, err := io.Copy(dst, resp.Body); err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync download file: %w", err)
+	}
+
+	if hasher != nil {
+		if sum := hex.EncodeToString(hasher.Sum(nil)); sum != config.checksum {
+			_ = os.Remove(partPath)
+			_ = os.Remove(metaPath(partPath))
+			return fmt.Errorf("failed to verify download: %w: got %s, want %s", ErrChecksumMismatch, sum, config.checksum)
+		}
+	}
+	return nil
+}
+
+// downloadMeta holds the validators of the response a partial download
+// started with
+type downloadMeta struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// validator returns the If-Range value for resuming. Weak ETags can't be
+// used with If-Range, so Last-Modified is used instead.
+func (m downloadMeta) validator() string {
+	if m.ETag != "" && !strings.HasPrefix(m.ETag, "W/") {
+		return m.ETag
+	}
+	return m.LastModified
+}
+
+// metaPath returns the path of the file holding the validators of partPath
+func metaPath(partPath string) string {
+	return partPath + ".meta"
+}
+
+// readDownloadMeta reads the validators of a partial download. A missing or
+// unreadable file yields no validators.
+func readDownloadMeta(partPath string) downloadMeta {
+	var meta downloadMeta
+	data, err := os.ReadFile(metaPath(partPath))
+	if err == nil {
+		_ = json.Unmarshal(data, &meta)
+	}
+	return meta
+}
+
+// writeDownloadMeta stores the validators of the response a download starts with
+func writeDownloadMeta(partPath string, header http.Header) error {
+	meta := downloadMeta{
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+	if meta.validator() == "" {
+		if err := os.Remove(metaPath(partPath)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove download metadata: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode download metadata: %w", err)
+	}
+	if err := os.WriteFile(metaPath(partPath), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write download metadata: %w", err)
+	}
+	return nil
+}
+
+// contentRangeStart parses the first byte position of "bytes 100-199/200"
+func contentRangeStart(value string) (int64, bool) {
+	value, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// progressWriter reports the bytes written through it
+type progressWriter struct {
+	w       io.Writer
+	written int64
+	total   int64
+	fn      ProgressFunc
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.fn(p.written, p.total)
+	return n, err
+}
@@ -0,0 +1,262 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fileServer serves content with Range and If-Range support. While cut is
+// positive, responses are cut off after that many bytes.
+type fileServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	content []byte
+	etag    string
+	cut     int
+	headers []http.Header
+}
+
+func newFileServer(t *testing.T, content, etag string) *fileServer {
+	t.Helper()
+	s := &fileServer{content: []byte(content), etag: etag}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fileServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	content, etag, cut := s.content, s.etag, s.cut
+	s.headers = append(s.headers, r.Header.Clone())
+	s.mu.Unlock()
+
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if cut > 0 {
+		// Promise the whole file but stop halfway, like a dropped connection
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(content[:cut])
+		return
+	}
+	http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+}
+
+func (s *fileServer) set(content, etag string, cut int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.content, s.etag, s.cut = []byte(content), etag, cut
+}
+
+func (s *fileServer) requests() []http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]http.Header(nil), s.headers...)
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func assertNotExist(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%s exists, want it removed", path)
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	server := newFileServer(t, content, `"v1"`)
+	path := filepath.Join(t.TempDir(), "file.bin")
+	client := NewWithConfig(Config{})
+
+	// The first attempt is interrupted halfway
+	server.set(content, `"v1"`, 400)
+	if err := client.Download(context.Background(), server.URL, path); err == nil {
+		t.Fatal("Download() of a truncated response succeeded")
+	}
+	if got := readFile(t, path+".part"); got != content[:400] {
+		t.Fatalf("partial file has %d bytes, want 400", len(got))
+	}
+	if got := readFile(t, path+".part.meta"); !strings.Contains(got, `\"v1\"`) {
+		t.Fatalf("metadata = %s, want the ETag", got)
+	}
+
+	// The next one resumes where it stopped
+	server.set(content, `"v1"`, 0)
+	var progress []int64
+	err := client.Download(context.Background(), server.URL, path, WithProgress(func(written, total int64) {
+		progress = append(progress, written)
+		if total != int64(len(content)) {
+			t.Errorf("total = %d, want %d", total, len(content))
+		}
+	}))
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+
+	if got := readFile(t, path); got != content {
+		t.Errorf("downloaded %d bytes, want the original %d", len(got), len(content))
+	}
+	header := server.requests()[1]
+	if header.Get("Range") != "bytes=400-" || header.Get("If-Range") != `"v1"` {
+		t.Errorf("resume request headers = %v, want Range from 400 and If-Range", header)
+	}
+	if len(progress) == 0 || progress[0] != 400 || progress[len(progress)-1] != int64(len(content)) {
+		t.Errorf("progress = %v, want from 400 to %d", progress, len(content))
+	}
+	assertNotExist(t, path+".part")
+	assertNotExist(t, path+".part.meta")
+}
+
+func TestDownloadRestartsWhenRemoteChanged(t *testing.T) {
+	server := newFileServer(t, "", "")
+	path := filepath.Join(t.TempDir(), "file.bin")
+	client := NewWithConfig(Config{})
+
+	server.set("old content that was here", `"v1"`, 10)
+	_ = client.Download(context.Background(), server.URL, path)
+
+	// The file changed: If-Range no longer matches, so the server sends it whole
+	server.set("brand new content", `"v2"`, 0)
+	if err := client.Download(context.Background(), server.URL, path); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if got := readFile(t, path); got != "brand new content" {
+		t.Errorf("file = %q, want the new content only", got)
+	}
+}
+
+func TestDownloadWithoutValidator(t *testing.T) {
+	server := newFileServer(t, "complete content", "")
+	path := filepath.Join(t.TempDir(), "file.bin")
+
+	// A partial file without metadata, e.g. left by an older version
+	if err := os.WriteFile(path+".part", []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewWithConfig(Config{})
+	if err := client.Download(context.Background(), server.URL, path); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if got := readFile(t, path); got != "complete content" {
+		t.Errorf("file = %q, want the whole remote file", got)
+	}
+	if header := server.requests()[0]; header.Get("Range") != "" {
+		t.Errorf("Range = %q, want no resume without a validator", header.Get("Range"))
+	}
+}
+
+func TestDownloadWeakETagUsesLastModified(t *testing.T) {
+	meta := downloadMeta{ETag: `W/"v1"`, LastModified: "Sat, 09 Aug 2025 10:00:00 GMT"}
+	if got := meta.validator(); got != meta.LastModified {
+		t.Errorf("validator() = %q, want Last-Modified for a weak ETag", got)
+	}
+	if got := (downloadMeta{ETag: `"v1"`, LastModified: meta.LastModified}).validator(); got != `"v1"` {
+		t.Errorf("validator() = %q, want the strong ETag", got)
+	}
+	if got := (downloadMeta{ETag: `W/"v1"`}).validator(); got != "" {
+		t.Errorf("validator() = %q, want none", got)
+	}
+}
+
+func TestDownloadChecksum(t *testing.T) {
+	content := "checked content"
+	sum := sha256.Sum256([]byte(content))
+	server := newFileServer(t, content, `"v1"`)
+	client := NewWithConfig(Config{})
+
+	path := filepath.Join(t.TempDir(), "ok.bin")
+	if err := client.Download(context.Background(), server.URL, path, WithChecksum(nil, strings.ToUpper(hex.EncodeToString(sum[:])))); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+
+	path = filepath.Join(t.TempDir(), "bad.bin")
+	err := client.Download(context.Background(), server.URL, path, WithChecksum(sha256.New, strings.Repeat("0", 64)))
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Download() error = %v, want ErrChecksumMismatch", err)
+	}
+	assertNotExist(t, path)
+	assertNotExist(t, path+".part")
+	assertNotExist(t, path+".part.meta")
+}
+
+// slowServer sends a body in two parts with a pause in between
+func slowServer(t *testing.T, pause time.Duration) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first "))
+		w.(http.Flusher).Flush()
+		time.Sleep(pause)
+		_, _ = w.Write([]byte("second"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStreamIgnoresClientTimeout(t *testing.T) {
+	server := slowServer(t, 200*time.Millisecond)
+	client := NewWithConfig(Config{Timeout: 50 * time.Millisecond})
+
+	// A buffered request is cut off by the timeout
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatal("Get() outlasted the client Timeout")
+	}
+
+	resp, err := client.Stream(context.Background(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "first second" {
+		t.Errorf("streamed body = %q, %v, want the whole body", body, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "slow.bin")
+	if err := client.Download(context.Background(), server.URL, path); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if got := readFile(t, path); got != "first second" {
+		t.Errorf("downloaded %q", got)
+	}
+}
+
+func TestStreamBoundedByContext(t *testing.T) {
+	server := slowServer(t, 500*time.Millisecond)
+	client := NewWithConfig(Config{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	resp, err := client.Stream(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("reading the body error = %v, want the context deadline", err)
+	}
+}
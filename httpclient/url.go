@@ -0,0 +1,228 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resolveURL resolves rawURL against Config.BaseURL. Absolute URLs are used
+// as is; other paths are appended to the base path, whether or not they
+// start with a slash.
+func (c *Client) resolveURL(rawURL string) (string, error) {
+	if c.config.BaseURL == "" {
+		return rawURL, nil
+	}
+
+	ref, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse url: %w", err)
+	}
+	if ref.IsAbs() {
+		return rawURL, nil
+	}
+
+	base, err := url.Parse(c.config.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse base url: %w", err)
+	}
+
+	// Keep the base path as a prefix: "https://api/v1" + "/users" is "https://api/v1/users"
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+		if base.RawPath != "" {
+			base.RawPath += "/"
+		}
+	}
+	ref.Path = strings.TrimLeft(ref.Path, "/")
+	ref.RawPath = strings.TrimLeft(ref.RawPath, "/")
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+// WithPathParam replaces the "{name}" placeholder in the request path with
+// value, escaping it so that e.g. a slash stays within the segment
+func WithPathParam(name, value string) RequestOption {
+	return WithPathParams(map[string]string{name: value})
+}
+
+// WithPathParams replaces several path placeholders, see WithPathParam. The
+// path is substituted in a single pass, so a value that looks like a
+// placeholder is never replaced in turn.
+func WithPathParams(params map[string]string) RequestOption {
+	return func(req *http.Request) {
+		if !strings.Contains(req.URL.Path, "{") {
+			return
+		}
+
+		// EscapedPath encodes the braces of the placeholders
+		escaped := substitutePath(req.URL.EscapedPath(), "%7B", "%7D", func(name string) (string, bool) {
+			name, err := url.PathUnescape(name)
+			if err != nil {
+				return "", false
+			}
+			value, ok := params[name]
+			return url.PathEscape(value), ok
+		})
+		req.URL.Path = substitutePath(req.URL.Path, "{", "}", func(name string) (string, bool) {
+			value, ok := params[name]
+			return value, ok
+		})
+		req.URL.RawPath = escaped
+	}
+}
+
+// substitutePath replaces each prefix+name+suffix in path with the value
+// returned for name, leaving placeholders without a value as they are
+func substitutePath(path, prefix, suffix string, value func(name string) (string, bool)) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(path, prefix)
+		if i < 0 {
+			break
+		}
+		j := strings.Index(path[i+len(prefix):], suffix)
+		if j < 0 {
+			break
+		}
+		j += i + len(prefix)
+
+		b.WriteString(path[:i])
+		if v, ok := value(path[i+len(prefix) : j]); ok {
+			b.WriteString(v)
+		} else {
+			b.WriteString(path[i : j+len(suffix)])
+		}
+		path = path[j+len(suffix):]
+	}
+	b.WriteString(path)
+	return b.String()
+}
+
+// WithQuery adds query parameters to the request. v is a url.Values, a
+// map[string]string, or a struct (or pointer to one) whose fields are named
+// by `url` tags:
+//
+//	type ListUsers struct {
+//		Page   int      `url:"page"`
+//		Search string   `url:"q,omitempty"`
+//		Tags   []string `url:"tag"`
+//	}
+//
+// Slices add the key once per element, nil pointers and fields tagged
+// `url:"-"` are skipped, and time.Time is formatted as RFC 3339.
+func WithQuery(v interface{}) RequestOption {
+	values := queryValues(v)
+	return func(req *http.Request) {
+		if len(values) == 0 {
+			return
+		}
+
+		query := req.URL.Query()
+		for key, vals := range values {
+			for _, val := range vals {
+				query.Add(key, val)
+			}
+		}
+		req.URL.RawQuery = query.Encode()
+	}
+}
+
+// queryValues converts the argument of WithQuery to url.Values
+func queryValues(v interface{}) url.Values {
+	switch q := v.(type) {
+	case nil:
+		return nil
+	case url.Values:
+		return q
+	case map[string]string:
+		values := make(url.Values, len(q))
+		for key, val := range q {
+			values.Set(key, val)
+		}
+		return values
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	values := make(url.Values)
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("url"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		omitEmpty := opts == "omitempty"
+
+		fv := rv.Field(i)
+		for fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Pointer || (omitEmpty && fv.IsZero()) {
+			continue
+		}
+
+		if (fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array) && fv.Type().Elem().Kind() != reflect.Uint8 {
+			for j := 0; j < fv.Len(); j++ {
+				values.Add(name, formatQueryValue(fv.Index(j)))
+			}
+			continue
+		}
+		values.Add(name, formatQueryValue(fv))
+	}
+	return values
+}
+
+// formatQueryValue formats a single query value
+func formatQueryValue(v reflect.Value) string {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+		return fmt.Sprint(v.Interface())
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
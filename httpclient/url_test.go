@@ -0,0 +1,67 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// pathServer records the escaped path of every request it gets
+func pathServer(t *testing.T, paths *[]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*paths = append(*paths, r.URL.EscapedPath())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWithPathParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		params map[string]string
+		want   string
+	}{
+		{"single", "/users/{id}", map[string]string{"id": "42"}, "/users/42"},
+		{"escaped value", "/files/{name}", map[string]string{"name": "a/b c"}, "/files/a%2Fb%20c"},
+		{"several", "/orgs/{org}/repos/{repo}", map[string]string{"org": "acme", "repo": "api"}, "/orgs/acme/repos/api"},
+		{"repeated", "/{id}/copy/{id}", map[string]string{"id": "7"}, "/7/copy/7"},
+		{"unknown kept", "/users/{id}/{rest}", map[string]string{"id": "42"}, "/users/42/%7Brest%7D"},
+		// A value is never substituted in turn, whatever the map order
+		{"placeholder value", "/orgs/{org}/repos/{repo}", map[string]string{"org": "{repo}", "repo": "{org}"}, "/orgs/%7Brepo%7D/repos/%7Borg%7D"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			server := pathServer(t, &paths)
+			client := NewWithConfig(Config{BaseURL: server.URL})
+
+			for i := 0; i < 20; i++ {
+				if _, err := client.Get(tt.path, WithPathParams(tt.params)); err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+			}
+			for _, got := range paths {
+				if got != tt.want {
+					t.Fatalf("path = %s, want %s", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWithPathParam(t *testing.T) {
+	var paths []string
+	server := pathServer(t, &paths)
+	client := NewWithConfig(Config{BaseURL: server.URL})
+
+	_, err := client.Get("/users/{id}/posts", WithPathParam("id", "a/b"), WithQuery(map[string]string{"page": "2"}))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "/users/a%2Fb/posts" {
+		t.Errorf("paths = %v, want the slash escaped within the segment", paths)
+	}
+}
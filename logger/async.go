@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultBufferSize is the async buffer capacity used when none is configured
+const defaultBufferSize = 1024
+
+// ErrWriterClosed is returned when writing to a closed AsyncWriter
+var ErrWriterClosed = errors.New("logger: writer is closed")
+
+// AsyncWriter writes entries to an underlying writer from a background
+// goroutine. Entries are held in a bounded ring buffer, and the drop policy
+// decides what happens when the buffer is full.
+type AsyncWriter struct {
+	w      io.Writer
+	policy DropPolicy
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	ring    [][]byte
+	head    int
+	count   int
+	writing bool
+	closed  bool
+
+	dropped atomic.Uint64
+	done    chan struct{}
+}
+
+// NewAsyncWriter creates an AsyncWriter buffering up to size entries for w
+func NewAsyncWriter(w io.Writer, size int, policy DropPolicy) *AsyncWriter {
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+
+	a := &AsyncWriter{
+		w:      w,
+		policy: parseDropPolicy(string(policy)),
+		ring:   make([][]byte, size),
+		done:   make(chan struct{}),
+	}
+	a.cond = sync.NewCond(&a.mu)
+
+	go a.run()
+	return a
+}
+
+// Write queues a copy of p. Each call is expected to hold one log entry.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	entry := make([]byte, len(p))
+	copy(entry, p)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for !a.closed && a.count == len(a.ring) {
+		switch a.policy {
+		case DropNewestPolicy:
+			a.dropped.Add(1)
+			return len(p), nil
+		case DropOldestPolicy:
+			a.ring[a.head] = nil
+			a.head = (a.head + 1) % len(a.ring)
+			a.count--
+			a.dropped.Add(1)
+		default:
+			a.cond.Wait()
+		}
+	}
+
+	if a.closed {
+		return 0, ErrWriterClosed
+	}
+
+	a.ring[(a.head+a.count)%len(a.ring)] = entry
+	a.count++
+	a.cond.Broadcast()
+
+	return len(p), nil
+}
+
+// Sync blocks until every buffered entry has been written, then syncs the
+// underlying writer when it supports it
+func (a *AsyncWriter) Sync() error {
+	a.mu.Lock()
+	for a.count > 0 || a.writing {
+		a.cond.Wait()
+	}
+	a.mu.Unlock()
+
+	return syncWriter(a.w)
+}
+
+// Close writes the remaining entries and stops the background goroutine.
+// The underlying writer is synced but not closed.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.cond.Broadcast()
+	a.mu.Unlock()
+
+	<-a.done
+	return syncWriter(a.w)
+}
+
+// Dropped returns the number of entries discarded because the buffer was full
+func (a *AsyncWriter) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// run writes queued entries until the writer is closed and drained
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for {
+		for a.count == 0 && !a.closed {
+			a.cond.Wait()
+		}
+		if a.count == 0 {
+			return
+		}
+
+		entry := a.ring[a.head]
+		a.ring[a.head] = nil
+		a.head = (a.head + 1) % len(a.ring)
+		a.count--
+		a.writing = true
+		a.cond.Broadcast()
+		a.mu.Unlock()
+
+		if _, err := a.w.Write(entry); err != nil {
+			// Nothing sensible to report a failed log write to
+			_ = err
+		}
+
+		a.mu.Lock()
+		a.writing = false
+		a.cond.Broadcast()
+	}
+}
+
+// parseDropPolicy converts a string to a DropPolicy, defaulting to BlockPolicy
+func parseDropPolicy(policy string) DropPolicy {
+	switch strings.ReplaceAll(strings.ToLower(policy), "_", "-") {
+	case string(DropNewestPolicy):
+		return DropNewestPolicy
+	case string(DropOldestPolicy):
+		return DropOldestPolicy
+	default:
+		return BlockPolicy
+	}
+}
@@ -0,0 +1,281 @@
+// Package audit provides a tamper-evident audit log.
+//
+// Every record is written as one JSON line holding the record, the SHA-256
+// hash of its exact bytes and, when a key is configured, an HMAC-SHA256
+// signature of those bytes:
+//
+//	{"record":{"seq":1,"time":"...","actor":"alice","action":"user.delete","prev_hash":""},"hash":"...","sig":"..."}
+//
+// Each record includes the hash of the previous one, so editing, removing or
+// reordering a record breaks the chain, which Verify detects. Without a key
+// anyone able to write the file could rebuild the whole chain, so use one
+// when the file itself isn't protected. Removing records from the end can
+// only be detected by comparing with a hash kept elsewhere, see LastHash.
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event is an audited action
+type Event struct {
+	// Actor is who performed the action, e.g. a user ID
+	Actor string
+	// Action is what was done, e.g. "user.delete"
+	Action string
+	// Target is what the action was performed on, e.g. "user:42"
+	Target string
+	// Fields holds additional details
+	Fields map[string]interface{}
+}
+
+// Record is an event as stored in the audit log
+type Record struct {
+	Seq      uint64                 `json:"seq"`
+	Time     time.Time              `json:"time"`
+	Actor    string                 `json:"actor"`
+	Action   string                 `json:"action"`
+	Target   string                 `json:"target,omitempty"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+	PrevHash string                 `json:"prev_hash"`
+}
+
+// line is the JSON layout of a line of the audit log. Record is kept as raw
+// bytes so the hash covers exactly what was written.
+type line struct {
+	Record json.RawMessage `json:"record"`
+	Hash   string          `json:"hash"`
+	Sig    string          `json:"sig,omitempty"`
+}
+
+// Config holds the audit logger configuration
+type Config struct {
+	// Filename is the audit log file, created if missing
+	Filename string
+	// Key signs every record with HMAC-SHA256 when set
+	Key []byte
+	// DisableSync skips the fsync after every record. Records may then be
+	// lost on a crash, but the chain stays verifiable.
+	DisableSync bool
+}
+
+// Logger appends hash-chained records to an audit log file. It is safe for
+// concurrent use.
+type Logger struct {
+	config Config
+
+	mu       sync.Mutex
+	file     *os.File
+	seq      uint64
+	lastHash string
+}
+
+// New opens the audit log, verifying the existing records so that a broken
+// chain is never extended, and continues the chain after the last record
+func New(config Config) (*Logger, error) {
+	if config.Filename == "" {
+		return nil, errors.New("audit log filename is required")
+	}
+
+	file, err := os.OpenFile(config.Filename, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	report, err := Verify(file, config.Key)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to verify audit log: %w", err)
+	}
+
+	return &Logger{
+		config:   config,
+		file:     file,
+		seq:      report.LastSeq,
+		lastHash: report.LastHash,
+	}, nil
+}
+
+// Log appends an event to the audit log
+func (l *Logger) Log(event Event) error {
+	if event.Action == "" {
+		return errors.New("audit event action is required")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return errors.New("audit log is closed")
+	}
+
+	record := Record{
+		Seq:      l.seq + 1,
+		Time:     time.Now().UTC(),
+		Actor:    event.Actor,
+		Action:   event.Action,
+		Target:   event.Target,
+		Fields:   event.Fields,
+		PrevHash: l.lastHash,
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	entry := line{
+		Record: data,
+		Hash:   hashRecord(data),
+	}
+	if len(l.config.Key) > 0 {
+		entry.Sig = signRecord(l.config.Key, data)
+	}
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	// One write per line keeps records whole with O_APPEND
+	if _, err := l.file.Write(append(encoded, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	if !l.config.DisableSync {
+		if err := l.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync audit log: %w", err)
+		}
+	}
+
+	l.seq = record.Seq
+	l.lastHash = entry.Hash
+	return nil
+}
+
+// LastHash returns the hash of the last record. Storing it elsewhere, e.g.
+// periodically in a database, allows detecting records removed from the end.
+func (l *Logger) LastHash() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastHash
+}
+
+// Close closes the audit log file
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Report summarizes a verified audit log
+type Report struct {
+	Records  int
+	LastSeq  uint64
+	LastHash string
+}
+
+// VerifyError describes the first problem found in an audit log
+type VerifyError struct {
+	// Line is the 1-based line number of the offending record
+	Line   int
+	Seq    uint64
+	Reason string
+}
+
+func (e *VerifyError) Error() string {
+	if e.Seq == 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+	}
+	return fmt.Sprintf("line %d (seq %d): %s", e.Line, e.Seq, e.Reason)
+}
+
+// Verify reads an audit log and checks that every record is intact, that
+// sequence numbers have no gaps and that each record links to the previous
+// one. When key is set every record must carry a valid signature. The first
+// problem is returned as a *VerifyError.
+func Verify(r io.Reader, key []byte) (Report, error) {
+	var report Report
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		fail := func(seq uint64, format string, args ...interface{}) (Report, error) {
+			return report, &VerifyError{Line: lineNo, Seq: seq, Reason: fmt.Sprintf(format, args...)}
+		}
+
+		var entry line
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || len(entry.Record) == 0 {
+			return fail(0, "malformed record")
+		}
+
+		var record Record
+		if err := json.Unmarshal(entry.Record, &record); err != nil {
+			return fail(0, "malformed record")
+		}
+
+		if hashRecord(entry.Record) != entry.Hash {
+			return fail(record.Seq, "record was modified: hash mismatch")
+		}
+		if len(key) > 0 {
+			if entry.Sig == "" {
+				return fail(record.Seq, "record is not signed")
+			}
+			if !hmac.Equal([]byte(signRecord(key, entry.Record)), []byte(entry.Sig)) {
+				return fail(record.Seq, "invalid signature")
+			}
+		}
+
+		if record.Seq != report.LastSeq+1 {
+			return fail(record.Seq, "expected seq %d: records are missing or out of order", report.LastSeq+1)
+		}
+		if record.PrevHash != report.LastHash {
+			return fail(record.Seq, "chain is broken: previous hash mismatch")
+		}
+
+		report.Records++
+		report.LastSeq = record.Seq
+		report.LastHash = entry.Hash
+	}
+
+	if err := scanner.Err(); err != nil {
+		return report, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return report, nil
+}
+
+// VerifyFile verifies the audit log at filename, see Verify
+func VerifyFile(filename string, key []byte) (Report, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	return Verify(file, key)
+}
+
+func hashRecord(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func signRecord(key, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,269 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testKey = []byte("audit-test-key")
+
+// writeLog logs n events to a new audit log and returns its lines
+func writeLog(t *testing.T, key []byte, n int) []string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := New(Config{Filename: filename, Key: key, DisableSync: true})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for i := 0; i < n; i++ {
+		if err := l.Log(Event{Actor: "alice", Action: "user.delete", Target: "user:42", Fields: map[string]interface{}{"i": i}}); err != nil {
+			t.Fatalf("Log() error = %v", err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// editRecord changes a record of an encoded line and optionally fixes its
+// hash, as someone rewriting the file without the key could
+func editRecord(t *testing.T, encoded string, edit func(*Record), rehash bool) string {
+	t.Helper()
+	var entry line
+	if err := json.Unmarshal([]byte(encoded), &entry); err != nil {
+		t.Fatal(err)
+	}
+	var record Record
+	if err := json.Unmarshal(entry.Record, &record); err != nil {
+		t.Fatal(err)
+	}
+	edit(&record)
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.Record = data
+	if rehash {
+		entry.Hash = hashRecord(data)
+	}
+	out, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func verifyLines(lines []string, key []byte) (Report, error) {
+	return Verify(strings.NewReader(strings.Join(lines, "\n")+"\n"), key)
+}
+
+func TestVerifyIntact(t *testing.T) {
+	for name, key := range map[string][]byte{"unsigned": nil, "signed": testKey} {
+		t.Run(name, func(t *testing.T) {
+			lines := writeLog(t, key, 3)
+
+			report, err := verifyLines(lines, key)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if report.Records != 3 || report.LastSeq != 3 || report.LastHash == "" {
+				t.Errorf("Verify() = %+v", report)
+			}
+		})
+	}
+
+	report, err := Verify(strings.NewReader(""), testKey)
+	if err != nil || report.Records != 0 {
+		t.Errorf("Verify(empty) = %+v, %v", report, err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	lines := writeLog(t, nil, 3)
+	signed := writeLog(t, testKey, 3)
+	changeActor := func(r *Record) { r.Actor = "mallory" }
+
+	tests := []struct {
+		name   string
+		lines  []string
+		key    []byte
+		line   int
+		reason string
+	}{
+		{
+			name:   "modified record",
+			lines:  []string{lines[0], editRecord(t, lines[1], changeActor, false), lines[2]},
+			line:   2,
+			reason: "hash mismatch",
+		},
+		{
+			name:   "modified record with a recomputed hash",
+			lines:  []string{lines[0], editRecord(t, lines[1], changeActor, true), lines[2]},
+			line:   3,
+			reason: "chain is broken",
+		},
+		{
+			name:   "modified signed record with a recomputed hash",
+			lines:  []string{signed[0], editRecord(t, signed[1], changeActor, true), signed[2]},
+			key:    testKey,
+			line:   2,
+			reason: "invalid signature",
+		},
+		{
+			name:   "removed record",
+			lines:  []string{lines[0], lines[2]},
+			line:   2,
+			reason: "expected seq 2",
+		},
+		{
+			name:   "reordered records",
+			lines:  []string{lines[0], lines[2], lines[1]},
+			line:   2,
+			reason: "expected seq 2",
+		},
+		{
+			name:   "renumbered records",
+			lines:  []string{lines[0], editRecord(t, lines[2], func(r *Record) { r.Seq = 2 }, true)},
+			line:   2,
+			reason: "chain is broken",
+		},
+		{
+			name:   "unsigned record with a key",
+			lines:  lines,
+			key:    testKey,
+			line:   1,
+			reason: "not signed",
+		},
+		{
+			name:   "wrong key",
+			lines:  signed,
+			key:    []byte("other-key"),
+			line:   1,
+			reason: "invalid signature",
+		},
+		{
+			name:   "malformed line",
+			lines:  []string{lines[0], "{not json"},
+			line:   2,
+			reason: "malformed record",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifyLines(tt.lines, tt.key)
+
+			var verifyErr *VerifyError
+			if !errors.As(err, &verifyErr) {
+				t.Fatalf("Verify() error = %v, want a *VerifyError", err)
+			}
+			if verifyErr.Line != tt.line || !strings.Contains(verifyErr.Reason, tt.reason) {
+				t.Errorf("Verify() error = %v, want line %d: %s", err, tt.line, tt.reason)
+			}
+		})
+	}
+}
+
+func TestVerifyReportsValidPrefix(t *testing.T) {
+	lines := writeLog(t, nil, 3)
+
+	report, err := verifyLines([]string{lines[0], lines[2]}, nil)
+	if err == nil {
+		t.Fatal("Verify() accepted a gap")
+	}
+	if report.Records != 1 || report.LastSeq != 1 {
+		t.Errorf("Verify() = %+v, want the records before the gap", report)
+	}
+	if got := err.Error(); got != "line 2 (seq 3): expected seq 2: records are missing or out of order" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestReopenContinuesChain(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := New(Config{Filename: filename, Key: testKey})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	_ = l.Log(Event{Actor: "alice", Action: "login"})
+	_ = l.Log(Event{Actor: "alice", Action: "logout"})
+	_ = l.Close()
+
+	l, err = New(Config{Filename: filename, Key: testKey})
+	if err != nil {
+		t.Fatalf("New() on an existing log error = %v", err)
+	}
+	if err := l.Log(Event{Actor: "bob", Action: "login"}); err != nil {
+		t.Fatalf("Log() error = %v", err)
+	}
+	lastHash := l.LastHash()
+	_ = l.Close()
+
+	report, err := VerifyFile(filename, testKey)
+	if err != nil {
+		t.Fatalf("VerifyFile() error = %v", err)
+	}
+	if report.Records != 3 || report.LastSeq != 3 || report.LastHash != lastHash {
+		t.Errorf("VerifyFile() = %+v, want 3 records ending with %s", report, lastHash)
+	}
+}
+
+func TestReopenRefusesBrokenChain(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	lines := writeLog(t, nil, 3)
+	tampered := []string{lines[0], lines[2]}
+	if err := os.WriteFile(filename, []byte(strings.Join(tampered, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(Config{Filename: filename}); err == nil {
+		t.Fatal("New() extended a broken chain")
+	}
+
+	// A different key than the one the log was written with is rejected as well
+	signed := filepath.Join(t.TempDir(), "signed.log")
+	l, err := New(Config{Filename: signed, Key: testKey})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	_ = l.Log(Event{Action: "login"})
+	_ = l.Close()
+
+	if _, err := New(Config{Filename: signed, Key: []byte("other-key")}); err == nil {
+		t.Error("New() accepted a log signed with another key")
+	}
+}
+
+func TestLoggerErrors(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Error("New() without a filename succeeded")
+	}
+
+	l, err := New(Config{Filename: filepath.Join(t.TempDir(), "audit.log")})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := l.Log(Event{Actor: "alice"}); err == nil {
+		t.Error("Log() without an action succeeded")
+	}
+	_ = l.Close()
+	if err := l.Log(Event{Action: "login"}); err == nil {
+		t.Error("Log() after Close succeeded")
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// ANSI escape sequences used by the console format
+const (
+	colorReset   = "\x1b[0m"
+	colorBold    = "\x1b[1m"
+	colorDim     = "\x1b[2m"
+	colorRed     = "\x1b[31m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+)
+
+// consoleIndent prefixes the lines printed below an entry
+const consoleIndent = "    "
+
+// formatConsole renders the entry for reading in a terminal:
+//
+//	15:04:05.000 INFO  payments  charge created  order_id=A1  api/pay.go:30
+//	    card={
+//	      "brand": "visa"
+//	    }
+//
+// Levels are padded to the same width, the name of a named logger follows
+// the level, and structured or multi-line field values are pretty-printed
+// on their own lines below the entry, followed by the stack trace.
+func formatConsole(e Entry, color bool) []byte {
+	var buf bytes.Buffer
+
+	paint := func(code, s string) {
+		if color {
+			buf.WriteString(code)
+			buf.WriteString(s)
+			buf.WriteString(colorReset)
+		} else {
+			buf.WriteString(s)
+		}
+	}
+
+	levelColor := consoleLevelColor(e.Level)
+
+	paint(colorDim, e.Time.Format("15:04:05.000"))
+	buf.WriteByte(' ')
+	paint(levelColor, padRight(strings.ToUpper(string(e.Level)), 5))
+	buf.WriteByte(' ')
+
+	if name, ok := e.Fields[NameField].(string); ok && name != "" {
+		paint(colorCyan, name)
+		buf.WriteString("  ")
+	}
+	paint(colorBold, e.Message)
+
+	type block struct{ key, value string }
+	var blocks []block
+	fields := textFields(e.Fields)
+	for _, key := range sortedFieldKeys(fields) {
+		if key == NameField {
+			continue
+		}
+		if value, ok := consoleBlock(fields[key]); ok {
+			blocks = append(blocks, block{key: key, value: value})
+			continue
+		}
+		buf.WriteString("  ")
+		paint(levelColor, logfmtKey(key))
+		buf.WriteByte('=')
+		buf.WriteString(logfmtValue(fieldString(fields[key])))
+	}
+
+	if e.Caller != "" {
+		buf.WriteString("  ")
+		paint(colorDim, e.Caller)
+	}
+
+	for _, b := range blocks {
+		buf.WriteByte('\n')
+		buf.WriteString(consoleIndent)
+		paint(levelColor, logfmtKey(b.key))
+		buf.WriteByte('=')
+		buf.WriteString(strings.ReplaceAll(b.value, "\n", "\n"+consoleIndent))
+	}
+
+	if e.Stack != "" {
+		buf.WriteByte('\n')
+		buf.WriteString(consoleIndent)
+		paint(colorDim, strings.ReplaceAll(e.Stack, "\n", "\n"+consoleIndent))
+	}
+
+	return buf.Bytes()
+}
+
+// consoleBlock returns the multi-line form of structured values and strings
+// containing newlines. Other values are printed inline.
+func consoleBlock(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, strings.Contains(v, "\n")
+	case json.Marshaler:
+	case error, interface{ String() string }:
+		s := fieldString(v)
+		return s, strings.Contains(s, "\n")
+	default:
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		default:
+			return "", false
+		}
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", false
+	}
+	return string(data), bytes.IndexByte(data, '\n') >= 0
+}
+
+// consoleLevelColor returns the color of a level in the console format
+func consoleLevelColor(level LogLevel) string {
+	switch level {
+	case DebugLevel:
+		return colorMagenta
+	case InfoLevel:
+		return colorBlue
+	case WarnLevel:
+		return colorYellow
+	case ErrorLevel:
+		return colorRed
+	case FatalLevel:
+		return colorBold + colorRed
+	default:
+		return colorReset
+	}
+}
+
+func padRight(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+// useColor reports whether w is a terminal that should get colors. Setting
+// the NO_COLOR environment variable disables colors everywhere.
+func useColor(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
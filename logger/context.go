@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"context"
+	"sync"
+)
+
+type loggerKey struct{}
+
+type requestIDKey struct{}
+
+var (
+	fallbackOnce   sync.Once
+	fallbackLogger Logger
+)
+
+// NewContext returns a copy of ctx carrying l
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, such as the request-scoped
+// logger injected by Middleware. Without one it returns a shared logger with
+// the default configuration.
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(loggerKey{}).(Logger); ok && l != nil {
+		return l
+	}
+	fallbackOnce.Do(func() {
+		fallbackLogger = New()
+	})
+	return fallbackLogger
+}
+
+// RequestIDFromContext returns the request ID set by Middleware, or ""
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
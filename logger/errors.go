@@ -0,0 +1,177 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// maxErrorChain limits how many wrapped errors are recorded per field
+const maxErrorChain = 16
+
+// maxStackDepth limits the number of frames in a captured stack trace
+const maxStackDepth = 32
+
+// ErrorInfo is the structured form in which error field values are logged
+type ErrorInfo struct {
+	Message string      `json:"message"`
+	Type    string      `json:"type"`
+	Chain   []ErrorLink `json:"chain,omitempty"`
+}
+
+// ErrorLink describes one wrapped error in an ErrorInfo chain
+type ErrorLink struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+// NewErrorInfo describes err along with every error it wraps, following
+// both Unwrap() error and Unwrap() []error
+func NewErrorInfo(err error) ErrorInfo {
+	info := ErrorInfo{
+		Message: err.Error(),
+		Type:    fmt.Sprintf("%T", err),
+	}
+
+	queue := unwrapAll(err)
+	for len(queue) > 0 && len(info.Chain) < maxErrorChain {
+		next := queue[0]
+		queue = append(queue[1:], unwrapAll(next)...)
+		info.Chain = append(info.Chain, ErrorLink{
+			Message: next.Error(),
+			Type:    fmt.Sprintf("%T", next),
+		})
+	}
+
+	return info
+}
+
+// String returns the error message. Text formats print the type and chain
+// as separate fields, see textFields.
+func (e ErrorInfo) String() string {
+	return e.Message
+}
+
+// chainString renders the chain as "message (type)" items separated by "; "
+func (e ErrorInfo) chainString() string {
+	links := make([]string, len(e.Chain))
+	for i, link := range e.Chain {
+		links[i] = link.Message + " (" + link.Type + ")"
+	}
+	return strings.Join(links, "; ")
+}
+
+// MarshalJSON keeps the structured form even where a backend would prefer
+// the String method
+func (e ErrorInfo) MarshalJSON() ([]byte, error) {
+	type plain ErrorInfo
+	return json.Marshal(plain(e))
+}
+
+// redact masks pattern matches in the error messages
+func (e ErrorInfo) redact(r *Redactor) ErrorInfo {
+	redacted := ErrorInfo{
+		Message: r.redactString(e.Message),
+		Type:    e.Type,
+	}
+	for _, link := range e.Chain {
+		redacted.Chain = append(redacted.Chain, ErrorLink{
+			Message: r.redactString(link.Message),
+			Type:    link.Type,
+		})
+	}
+	return redacted
+}
+
+// textFields returns the fields with every ErrorInfo split into its message
+// under the same key plus "<key>.type" and "<key>.chain", for the formats
+// that print values as plain text. fields is returned as is when it holds no
+// ErrorInfo.
+func textFields(fields map[string]interface{}) map[string]interface{} {
+	expanded := fields
+	copied := false
+	for key, value := range fields {
+		info, ok := value.(ErrorInfo)
+		if !ok {
+			continue
+		}
+		if !copied {
+			expanded = make(map[string]interface{}, len(fields)+2)
+			for k, v := range fields {
+				expanded[k] = v
+			}
+			copied = true
+		}
+		expanded[key] = info.Message
+		expanded[key+".type"] = info.Type
+		if len(info.Chain) > 0 {
+			expanded[key+".chain"] = info.chainString()
+		}
+	}
+	return expanded
+}
+
+func unwrapAll(err error) []error {
+	switch wrapped := err.(type) {
+	case interface{ Unwrap() []error }:
+		return wrapped.Unwrap()
+	default:
+		if inner := errors.Unwrap(err); inner != nil {
+			return []error{inner}
+		}
+		return nil
+	}
+}
+
+// callSite describes where an entry was logged from
+type callSite struct {
+	// frame is zero when caller reporting is disabled
+	frame runtime.Frame
+	// stack is set for entries that capture a stack trace
+	stack string
+}
+
+// caller returns the call site as "dir/file.go:line", or "" when unknown
+func (c callSite) caller() string {
+	if c.frame.PC == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(c.frame.File)), filepath.Base(c.frame.File), c.frame.Line)
+}
+
+// callerFrame returns the frame skip levels above the function calling callerFrame
+func callerFrame(skip int) runtime.Frame {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return runtime.Frame{}
+	}
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	return frame
+}
+
+// stackTrace formats the stack starting skip levels above the function
+// calling stackTrace, in the same layout zap uses
+func stackTrace(skip int) string {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+2, pcs)
+	if n == 0 {
+		return ""
+	}
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var b strings.Builder
+	for {
+		frame, more := frames.Next()
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
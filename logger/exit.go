@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultShutdownTimeout bounds how long Fatal waits for the logger flush and
+// the registered cleanups when Config.ShutdownTimeout is not set
+const defaultShutdownTimeout = 10 * time.Second
+
+// cleanups holds the functions registered with OnShutdown
+var cleanups struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+// OnShutdown registers a cleanup to run when a Fatal entry terminates the
+// process, after the logger has been flushed. Cleanups run once, in reverse
+// order of registration like deferred calls, and a panicking cleanup doesn't
+// prevent the others from running.
+func OnShutdown(cleanup func()) {
+	if cleanup == nil {
+		return
+	}
+	cleanups.mu.Lock()
+	cleanups.fns = append(cleanups.fns, cleanup)
+	cleanups.mu.Unlock()
+}
+
+// WithExitFunc replaces os.Exit as the function called after a Fatal entry,
+// e.g. to assert on it in tests
+func WithExitFunc(exit func(code int)) Option {
+	return func(c *Config) {
+		c.ExitFunc = exit
+	}
+}
+
+// runCleanups runs and clears the registered cleanups, giving up after
+// timeout so a stuck cleanup can't keep the process alive
+func runCleanups(timeout time.Duration) {
+	cleanups.mu.Lock()
+	fns := cleanups.fns
+	cleanups.fns = nil
+	cleanups.mu.Unlock()
+
+	if len(fns) == 0 {
+		return
+	}
+
+	runWithTimeout(timeout, func() {
+		for i := len(fns) - 1; i >= 0; i-- {
+			runCleanup(fns[i])
+		}
+	})
+}
+
+// runWithTimeout waits at most timeout for fn, which keeps running in the
+// background when it takes longer
+func runWithTimeout(timeout time.Duration, fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	}
+}
+
+func runCleanup(fn func()) {
+	defer func() {
+		// A failing cleanup must not stop the process from exiting
+		_ = recover()
+	}()
+	fn()
+}
+
+// exit runs the shutdown sequence after a Fatal entry: flush the logger,
+// run the registered cleanups and call the exit function. Both steps share
+// the shutdown timeout, so an unreachable hook or shipper endpoint can't
+// delay the exit beyond it.
+//
+// With the default os.Exit the output and sinks are closed. A custom exit
+// function only gets them synced, so a logger whose exit function returns,
+// as in tests, keeps working.
+func (p *pipeline) exit(code int) {
+	deadline := time.Now().Add(p.shutdownTimeout)
+
+	flush := p.sync
+	if p.exitFunc == nil {
+		flush = p.close
+	}
+	runWithTimeout(p.shutdownTimeout, func() { _ = flush() })
+	runCleanups(time.Until(deadline))
+
+	if p.exitFunc == nil {
+		os.Exit(code)
+	}
+	p.exitFunc(code)
+}
@@ -0,0 +1,227 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// reservedKeys are the JSON keys used for the entry itself. Fields with the
+// same name are written with a "fields." prefix, as logrus does.
+var reservedKeys = map[string]bool{"time": true, "level": true, "msg": true, "caller": true, "stacktrace": true}
+
+// fieldName returns the key a field is written under. Reserved keys get the
+// "fields." prefix, repeated while another field already has that name, so
+// no key is written twice.
+func fieldName(key string, fields map[string]interface{}) string {
+	if !reservedKeys[key] {
+		return key
+	}
+	name := "fields." + key
+	for {
+		if _, taken := fields[name]; !taken {
+			return name
+		}
+		name = "fields." + name
+	}
+}
+
+// formatJSON renders the entry as a single JSON object. The time, level,
+// msg and caller keys come first, followed by the fields in sorted order
+// and the stack trace.
+func formatJSON(e Entry) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString(`{"time":`)
+	writeJSONValue(&buf, e.Time.Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSONValue(&buf, string(e.Level))
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(&buf, e.Message)
+	if e.Caller != "" {
+		buf.WriteString(`,"caller":`)
+		writeJSONValue(&buf, e.Caller)
+	}
+
+	for _, key := range sortedFieldKeys(e.Fields) {
+		name := fieldName(key, e.Fields)
+		buf.WriteByte(',')
+		writeJSONValue(&buf, name)
+		buf.WriteByte(':')
+		writeJSONValue(&buf, jsonFieldValue(e.Fields[key]))
+	}
+
+	if e.Stack != "" {
+		buf.WriteString(`,"stacktrace":`)
+		writeJSONValue(&buf, e.Stack)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// formatLogfmt renders the entry as logfmt key=value pairs in the same order
+// as formatJSON. Values are quoted when they contain spaces, quotes, "=" or
+// non-printable characters.
+func formatLogfmt(e Entry) []byte {
+	var buf bytes.Buffer
+	fields := textFields(e.Fields)
+
+	writeLogfmtPair(&buf, "time", e.Time.Format(time.RFC3339Nano))
+	writeLogfmtPair(&buf, "level", string(e.Level))
+	writeLogfmtPair(&buf, "msg", e.Message)
+	if e.Caller != "" {
+		writeLogfmtPair(&buf, "caller", e.Caller)
+	}
+
+	for _, key := range sortedFieldKeys(fields) {
+		name := fieldName(key, fields)
+		writeLogfmtPair(&buf, name, fieldString(fields[key]))
+	}
+
+	if e.Stack != "" {
+		writeLogfmtPair(&buf, "stacktrace", e.Stack)
+	}
+
+	return buf.Bytes()
+}
+
+// entryFormatter returns the formatter used by every backend for the json,
+// logfmt and console formats, or nil for text. color only affects console.
+func entryFormatter(format string, color bool) func(Entry) []byte {
+	switch LogFormat(format) {
+	case JSONFormat:
+		return formatJSON
+	case LogfmtFormat:
+		return formatLogfmt
+	case ConsoleFormat:
+		return func(e Entry) []byte {
+			return formatConsole(e, color)
+		}
+	default:
+		return nil
+	}
+}
+
+// formatText renders the entry as "[LEVEL] caller: msg k=v" with sorted
+// fields, followed by the stack trace on the next lines
+func formatText(e Entry) string {
+	var b strings.Builder
+
+	b.WriteString("[")
+	b.WriteString(strings.ToUpper(string(e.Level)))
+	b.WriteString("] ")
+	if e.Caller != "" {
+		b.WriteString(e.Caller)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Message)
+
+	fields := textFields(e.Fields)
+	for _, key := range sortedFieldKeys(fields) {
+		fmt.Fprintf(&b, " %s=%v", key, fields[key])
+	}
+
+	if e.Stack != "" {
+		b.WriteByte('\n')
+		b.WriteString(e.Stack)
+	}
+
+	return b.String()
+}
+
+func writeLogfmtPair(buf *bytes.Buffer, key, value string) {
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(logfmtKey(key))
+	buf.WriteByte('=')
+	buf.WriteString(logfmtValue(value))
+}
+
+// logfmtKey replaces the characters a logfmt key can't contain
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// logfmtValue quotes a value when it would otherwise be ambiguous
+func logfmtValue(value string) string {
+	if value == "" {
+		return `""`
+	}
+	if strings.IndexFunc(value, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r)
+	}) >= 0 {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// jsonFieldValue converts values that don't marshal usefully, such as errors
+func jsonFieldValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Marshaler:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
+}
+
+// writeJSONValue marshals v into buf, falling back to its string form
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(data)
+}
+
+func sortedFieldKeys(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fieldString renders a field value as plain text for outputs without a
+// native representation for structured values
+func fieldString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	case nil:
+		return ""
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		if data, err := json.Marshal(value); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,275 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEntry is an entry with a wrapped error field
+func testEntry() Entry {
+	err := fmt.Errorf("charge failed: %w", errors.New("card declined"))
+	return Entry{
+		Time:    time.Date(2025, 8, 9, 10, 0, 0, 0, time.UTC),
+		Level:   ErrorLevel,
+		Message: "payment failed",
+		Caller:  "api/pay.go:30",
+		Fields: map[string]interface{}{
+			"order_id": "A1",
+			"error":    NewErrorInfo(err),
+		},
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	var got map[string]interface{}
+	if err := json.Unmarshal(formatJSON(testEntry()), &got); err != nil {
+		t.Fatalf("formatJSON() is not valid JSON: %v", err)
+	}
+
+	if got["time"] != "2025-08-09T10:00:00Z" || got["level"] != "error" || got["msg"] != "payment failed" || got["caller"] != "api/pay.go:30" {
+		t.Errorf("formatJSON() = %v", got)
+	}
+	errInfo, ok := got["error"].(map[string]interface{})
+	if !ok || errInfo["type"] != "*fmt.wrapError" || errInfo["message"] != "charge failed: card declined" {
+		t.Fatalf("error field = %v, want the structured error", got["error"])
+	}
+	if chain, ok := errInfo["chain"].([]interface{}); !ok || len(chain) != 1 {
+		t.Errorf("error chain = %v, want one link", errInfo["chain"])
+	}
+}
+
+func TestFormatJSONKeyOrder(t *testing.T) {
+	e := Entry{Time: time.Unix(0, 0).UTC(), Level: InfoLevel, Message: "m", Fields: map[string]interface{}{"b": 1, "a": 2}}
+	want := `{"time":"1970-01-01T00:00:00Z","level":"info","msg":"m","a":2,"b":1}`
+	if got := string(formatJSON(e)); got != want {
+		t.Errorf("formatJSON() = %s, want %s", got, want)
+	}
+}
+
+func TestTextFormatsSplitErrors(t *testing.T) {
+	e := testEntry()
+
+	tests := []struct {
+		name string
+		got  string
+		want []string
+	}{
+		{
+			name: "text",
+			got:  formatText(e),
+			want: []string{
+				"error=charge failed: card declined",
+				"error.type=*fmt.wrapError",
+				"error.chain=card declined (*errors.errorString)",
+			},
+		},
+		{
+			name: "logfmt",
+			got:  string(formatLogfmt(e)),
+			want: []string{
+				`error="charge failed: card declined"`,
+				"error.type=*fmt.wrapError",
+				`error.chain="card declined (*errors.errorString)"`,
+			},
+		},
+		{
+			name: "console",
+			got:  string(formatConsole(e, false)),
+			want: []string{
+				`error="charge failed: card declined"`,
+				"error.type=*fmt.wrapError",
+				`error.chain="card declined (*errors.errorString)"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, want := range tt.want {
+				if !strings.Contains(tt.got, want) {
+					t.Errorf("output %q does not contain %q", tt.got, want)
+				}
+			}
+		})
+	}
+
+	// The entry itself is left untouched
+	if _, ok := e.Fields["error"].(ErrorInfo); !ok || len(e.Fields) != 2 {
+		t.Errorf("entry fields were modified: %v", e.Fields)
+	}
+}
+
+func TestTextFieldsWithoutErrors(t *testing.T) {
+	fields := map[string]interface{}{"a": 1}
+	if got := textFields(fields); len(got) != 1 || got["a"] != 1 {
+		t.Errorf("textFields() = %v, want the fields unchanged", got)
+	}
+
+	plain := NewErrorInfo(errors.New("boom"))
+	got := textFields(map[string]interface{}{"err": plain})
+	if got["err"] != "boom" || got["err.type"] != "*errors.errorString" {
+		t.Errorf("textFields() = %v", got)
+	}
+	if _, ok := got["err.chain"]; ok {
+		t.Errorf("textFields() = %v, want no chain for an unwrapped error", got)
+	}
+}
+
+func TestFormatLogfmtQuoting(t *testing.T) {
+	e := Entry{
+		Time:    time.Unix(0, 0).UTC(),
+		Level:   InfoLevel,
+		Message: "hello world",
+		Fields: map[string]interface{}{
+			"empty":   "",
+			"quote":   `say "hi"`,
+			"bad key": "v",
+			"list":    []int{1, 2},
+		},
+	}
+	want := `time=1970-01-01T00:00:00Z level=info msg="hello world" bad_key=v empty="" list=[1,2] quote="say \"hi\""`
+	if got := string(formatLogfmt(e)); got != want {
+		t.Errorf("formatLogfmt() = %s, want %s", got, want)
+	}
+}
+
+func TestFieldString(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{"s", "s"},
+		{nil, ""},
+		{errors.New("boom"), "boom"},
+		{time.Second, "1s"},
+		{42, "42"},
+		{map[string]int{"a": 1}, `{"a":1}`},
+		{[]string{"a"}, `["a"]`},
+	}
+	for _, tt := range tests {
+		if got := fieldString(tt.value); got != tt.want {
+			t.Errorf("fieldString(%#v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSinkFormatsSplitErrors(t *testing.T) {
+	e := testEntry()
+
+	syslog := (&SyslogSink{facility: 1, hostname: "host", appName: "app", pid: 1}).format(e)
+	for _, want := range []string{`error="charge failed: card declined"`, `error.type="*fmt.wrapError"`, `error.chain="card declined (*errors.errorString)"`} {
+		if !strings.Contains(syslog, want) {
+			t.Errorf("syslog message %q does not contain %q", syslog, want)
+		}
+	}
+
+	journal := string((&JournaldSink{identifier: "app"}).format(e))
+	for _, want := range []string{"ERROR=charge failed: card declined\n", "ERROR_TYPE=*fmt.wrapError\n", "ERROR_CHAIN=card declined (*errors.errorString)\n"} {
+		if !strings.Contains(journal, want) {
+			t.Errorf("journald message %q does not contain %q", journal, want)
+		}
+	}
+}
+
+func TestLoggerTextErrors(t *testing.T) {
+	for _, backend := range []string{"simple", "logrus", "zap"} {
+		for _, format := range []string{"text", "logfmt", "console"} {
+			if backend == "zap" && format == "text" {
+				// zap's console encoder prints the structured error as JSON
+				continue
+			}
+			t.Run(backend+"/"+format, func(t *testing.T) {
+				l, output := newRawTestLogger(t, Config{Backend: backend, Format: format})
+				l.Error("payment failed", "error", fmt.Errorf("charge failed: %w", errors.New("card declined")))
+
+				out := output()
+				// logrus' text formatter quotes values containing "*"
+				if !strings.Contains(out, "error.type=*fmt.wrapError") && !strings.Contains(out, `error.type="*fmt.wrapError"`) {
+					t.Errorf("output %q has no error type", out)
+				}
+				if !strings.Contains(out, "card declined (*errors.errorString)") {
+					t.Errorf("output %q has no error chain", out)
+				}
+			})
+		}
+	}
+}
+
+func TestFieldNameReservedKeys(t *testing.T) {
+	fields := map[string]interface{}{"level": 1, "fields.level": 2, "msg": 3, "user": 4}
+
+	tests := map[string]string{
+		"user":         "user",
+		"fields.level": "fields.level",
+		"msg":          "fields.msg",
+		// fields.level is taken by a user field
+		"level": "fields.fields.level",
+	}
+	for key, want := range tests {
+		if got := fieldName(key, fields); got != want {
+			t.Errorf("fieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLoggerReservedKeys(t *testing.T) {
+	for _, backend := range []string{"simple", "zap"} {
+		for _, format := range []string{"json", "logfmt"} {
+			t.Run(backend+"/"+format, func(t *testing.T) {
+				l, output := newRawTestLogger(t, Config{Backend: backend, Format: format})
+				l.Info("hello", "level", "user", "fields.level", "nested")
+
+				out := output()
+				for _, key := range []string{"level", "fields.level", "fields.fields.level"} {
+					var n int
+					if format == "json" {
+						n = strings.Count(out, `"`+key+`":`)
+					} else {
+						n = strings.Count(" "+out, " "+key+"=")
+					}
+					if n != 1 {
+						t.Errorf("output %q has key %s %d times, want once", out, key, n)
+					}
+				}
+				if format == "json" {
+					var entry map[string]interface{}
+					if err := json.Unmarshal([]byte(out), &entry); err != nil {
+						t.Fatalf("output is not valid JSON: %v", err)
+					}
+					if entry["level"] != "info" || entry["fields.fields.level"] != "user" || entry["fields.level"] != "nested" {
+						t.Errorf("entry = %v", entry)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestZapJSONMatchesSimple(t *testing.T) {
+	for _, backend := range []string{"simple", "zap"} {
+		t.Run(backend, func(t *testing.T) {
+			l, entries := newTestLogger(t, Config{Backend: backend})
+			l.Named("payments").Info("charged", "amount", 10)
+
+			got := entries()
+			if len(got) != 1 {
+				t.Fatalf("got %d entries, want 1", len(got))
+			}
+			for _, key := range []string{"time", "level", "msg", "caller"} {
+				if _, ok := got[0][key]; !ok {
+					t.Errorf("entry %v has no %s", got[0], key)
+				}
+			}
+			if got[0][NameField] != "payments" || got[0]["amount"] != float64(10) {
+				t.Errorf("entry = %v", got[0])
+			}
+			if _, ok := got[0]["ts"]; ok {
+				t.Errorf("entry %v uses zap's ts key", got[0])
+			}
+		})
+	}
+}
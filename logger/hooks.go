@@ -0,0 +1,295 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultHookBufferSize is the per-hook queue capacity used when none is configured
+const defaultHookBufferSize = 256
+
+// webhookTimeout bounds a single WebhookHook request
+const webhookTimeout = 5 * time.Second
+
+// ErrorLevels are the levels alerting hooks usually subscribe to
+var ErrorLevels = []LogLevel{ErrorLevel, FatalLevel}
+
+// ErrHookChannelFull is returned by ChannelHook.Fire when nobody is
+// receiving from the channel
+var ErrHookChannelFull = errors.New("hook channel is full")
+
+// Hook is notified of every entry logged at one of its levels.
+//
+// Fire is called from a goroutine dedicated to the hook, so a slow hook
+// doesn't slow down logging. Entries arriving faster than Config.HookRateLimit
+// or while the hook's queue is full are dropped and counted in DroppedEntries.
+type Hook interface {
+	// Levels returns the levels the hook fires for. Empty means every level.
+	Levels() []LogLevel
+	Fire(entry Entry) error
+}
+
+// Option adjusts the configuration passed to New and NewWithConfig
+type Option func(*Config)
+
+// WithHooks registers hooks in addition to those in Config.Hooks
+func WithHooks(hooks ...Hook) Option {
+	return func(c *Config) {
+		c.Hooks = append(c.Hooks, hooks...)
+	}
+}
+
+// hookMessage is an entry to fire, or a flush marker when flushed is set
+type hookMessage struct {
+	entry   Entry
+	flushed chan struct{}
+}
+
+// hookSink delivers entries to a single hook from a background goroutine,
+// with its own queue and rate limit
+type hookSink struct {
+	hook    Hook
+	levels  map[LogLevel]bool
+	limiter *tokenBucket
+
+	// mu guards closing messages against concurrent sends
+	mu       sync.RWMutex
+	closed   bool
+	messages chan hookMessage
+	done     chan struct{}
+
+	dropped atomic.Uint64
+}
+
+// newHookSink starts the delivery goroutine of a hook
+func newHookSink(hook Hook, config Config) *hookSink {
+	size := config.HookBufferSize
+	if size <= 0 {
+		size = defaultHookBufferSize
+	}
+
+	h := &hookSink{
+		hook:     hook,
+		messages: make(chan hookMessage, size),
+		done:     make(chan struct{}),
+	}
+	if levels := hook.Levels(); len(levels) > 0 {
+		h.levels = make(map[LogLevel]bool, len(levels))
+		for _, level := range levels {
+			h.levels[level] = true
+		}
+	}
+	if config.HookRateLimit > 0 {
+		h.limiter = newTokenBucket(config.HookRateLimit, config.HookBurst)
+	}
+
+	go h.run()
+	return h
+}
+
+// WriteEntry queues the entry if the hook fires for its level
+func (h *hookSink) WriteEntry(entry Entry) error {
+	if h.levels != nil && !h.levels[entry.Level] {
+		return nil
+	}
+	if h.limiter != nil && !h.limiter.allow() {
+		h.dropped.Add(1)
+		return nil
+	}
+
+	// Hooks run asynchronously, so they get their own copy of the fields
+	fields := make(map[string]interface{}, len(entry.Fields))
+	for k, v := range entry.Fields {
+		fields[k] = v
+	}
+	entry.Fields = fields
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return ErrWriterClosed
+	}
+	select {
+	case h.messages <- hookMessage{entry: entry}:
+	default:
+		h.dropped.Add(1)
+	}
+	return nil
+}
+
+// Sync waits until the entries queued so far have been fired
+func (h *hookSink) Sync() error {
+	flushed := make(chan struct{})
+
+	h.mu.RLock()
+	if h.closed {
+		h.mu.RUnlock()
+		return nil
+	}
+	h.messages <- hookMessage{flushed: flushed}
+	h.mu.RUnlock()
+
+	<-flushed
+	return nil
+}
+
+// Close fires the queued entries and stops the delivery goroutine
+func (h *hookSink) Close() error {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.messages)
+	}
+	h.mu.Unlock()
+
+	<-h.done
+	return nil
+}
+
+// Dropped returns the number of entries skipped by the rate limit or
+// because the queue was full
+func (h *hookSink) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+func (h *hookSink) run() {
+	defer close(h.done)
+
+	for msg := range h.messages {
+		if msg.flushed != nil {
+			close(msg.flushed)
+			continue
+		}
+		if err := h.hook.Fire(msg.entry); err != nil {
+			// A failing hook must not affect logging
+			_ = err
+		}
+	}
+}
+
+// tokenBucket allows rate events per second with bursts of up to burst events
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// allow takes a token if one is available
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// ChannelHook sends entries to a channel for in-process handling. Entries
+// are dropped when the channel is full.
+type ChannelHook struct {
+	ch     chan<- Entry
+	levels []LogLevel
+}
+
+// NewChannelHook creates a hook sending entries at levels to ch. No levels
+// means every level.
+func NewChannelHook(ch chan<- Entry, levels ...LogLevel) *ChannelHook {
+	return &ChannelHook{ch: ch, levels: levels}
+}
+
+// Levels implements Hook
+func (c *ChannelHook) Levels() []LogLevel {
+	return c.levels
+}
+
+// Fire implements Hook
+func (c *ChannelHook) Fire(entry Entry) error {
+	select {
+	case c.ch <- entry:
+		return nil
+	default:
+		return ErrHookChannelFull
+	}
+}
+
+// WebhookHook posts entries as JSON objects, in the same layout as the json
+// format, to an HTTP endpoint such as an alerting webhook
+type WebhookHook struct {
+	url     string
+	levels  []LogLevel
+	client  *http.Client
+	headers map[string]string
+}
+
+// NewWebhookHook creates a hook posting entries at levels to url. No levels
+// means every level.
+func NewWebhookHook(url string, levels ...LogLevel) *WebhookHook {
+	return &WebhookHook{
+		url:     url,
+		levels:  levels,
+		client:  &http.Client{Timeout: webhookTimeout},
+		headers: make(map[string]string),
+	}
+}
+
+// WithHeader adds a header, e.g. for authentication, to every request
+func (w *WebhookHook) WithHeader(key, value string) *WebhookHook {
+	w.headers[key] = value
+	return w
+}
+
+// Levels implements Hook
+func (w *WebhookHook) Levels() []LogLevel {
+	return w.levels
+}
+
+// Fire implements Hook
+func (w *WebhookHook) Fire(entry Entry) error {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(formatJSON(entry)))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultJournaldSocket is the native protocol socket of systemd-journald
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+// journalReservedFields are written by the sink itself. Entry fields with
+// the same name get a "FIELD_" prefix.
+var journalReservedFields = map[string]bool{
+	"MESSAGE": true, "PRIORITY": true, "SYSLOG_IDENTIFIER": true,
+	"CODE_FILE": true, "CODE_LINE": true, "STACKTRACE": true,
+}
+
+// JournaldSink sends entries to systemd-journald using its native protocol,
+// with every field stored as a journal field.
+//
+// Each entry is sent as a single datagram, so entries larger than the
+// socket's datagram limit are rejected by the kernel.
+type JournaldSink struct {
+	socket     string
+	identifier string
+
+	mu      sync.Mutex
+	conn    net.Conn
+	backoff reconnectBackoff
+}
+
+// NewJournaldSink connects to the journald socket. An empty socket uses
+// DefaultJournaldSocket and an empty identifier the executable name.
+func NewJournaldSink(socket, identifier string) (*JournaldSink, error) {
+	if socket == "" {
+		socket = DefaultJournaldSocket
+	}
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+
+	j := &JournaldSink{
+		socket:     socket,
+		identifier: identifier,
+	}
+	if err := j.connect(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// WriteEntry sends the entry, reconnecting once if the connection was lost.
+// Like SyslogSink, reconnecting is retried with a growing delay while
+// journald stays unreachable, and entries are dropped in between.
+func (j *JournaldSink) WriteEntry(entry Entry) error {
+	msg := j.format(entry)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.conn != nil {
+		if _, err := j.conn.Write(msg); err == nil {
+			return nil
+		}
+		_ = j.conn.Close()
+		j.conn = nil
+	}
+
+	if err := j.backoff.reconnect(j.connect); err != nil {
+		return err
+	}
+	_, err := j.conn.Write(msg)
+	return err
+}
+
+// Dropped returns the number of entries discarded while journald was
+// unreachable
+func (j *JournaldSink) Dropped() uint64 {
+	return j.backoff.dropped.Load()
+}
+
+// Sync is a no-op since every entry is sent immediately
+func (j *JournaldSink) Sync() error {
+	return nil
+}
+
+// Close closes the journald socket
+func (j *JournaldSink) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.conn == nil {
+		return nil
+	}
+	err := j.conn.Close()
+	j.conn = nil
+	return err
+}
+
+func (j *JournaldSink) connect() error {
+	conn, err := net.Dial("unixgram", j.socket)
+	if err != nil {
+		return fmt.Errorf("failed to connect to journald at %s: %w", j.socket, err)
+	}
+	j.conn = conn
+	return nil
+}
+
+// format encodes the entry in the journal native protocol
+func (j *JournaldSink) format(entry Entry) []byte {
+	var buf bytes.Buffer
+
+	writeJournalField(&buf, "MESSAGE", entry.Message)
+	writeJournalField(&buf, "PRIORITY", fmt.Sprint(syslogSeverity(entry.Level)))
+	writeJournalField(&buf, "SYSLOG_IDENTIFIER", j.identifier)
+
+	if entry.Caller != "" {
+		if i := strings.LastIndexByte(entry.Caller, ':'); i > 0 {
+			writeJournalField(&buf, "CODE_FILE", entry.Caller[:i])
+			writeJournalField(&buf, "CODE_LINE", entry.Caller[i+1:])
+		}
+	}
+	if entry.Stack != "" {
+		writeJournalField(&buf, "STACKTRACE", entry.Stack)
+	}
+
+	fields := textFields(entry.Fields)
+	for _, key := range sortedFieldKeys(fields) {
+		if name := journalFieldName(key); name != "" {
+			writeJournalField(&buf, name, fieldString(fields[key]))
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// writeJournalField writes KEY=value, or the length-prefixed binary form
+// for values containing newlines
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name)
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalFieldName converts a field key to a valid journal field name:
+// upper case letters, digits and underscores, not starting with an
+// underscore or digit, and at most 64 characters
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	name = strings.TrimLeft(name, "_0123456789")
+	if journalReservedFields[name] {
+		name = "FIELD_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournaldSink(t *testing.T) {
+	conn, path := listenUnixgram(t)
+
+	sink, err := NewJournaldSink(path, "payments")
+	if err != nil {
+		t.Fatalf("NewJournaldSink() error = %v", err)
+	}
+	defer sink.Close()
+
+	entry := Entry{
+		Time:    time.Now(),
+		Level:   ErrorLevel,
+		Message: "charge failed",
+		Caller:  "api/pay.go:30",
+		Fields:  map[string]interface{}{"order-id": "A1", "message": "user", "_private": 1, "9lives": true},
+	}
+	if err := sink.WriteEntry(entry); err != nil {
+		t.Fatalf("WriteEntry() error = %v", err)
+	}
+
+	want := "MESSAGE=charge failed\n" +
+		"PRIORITY=3\n" +
+		"SYSLOG_IDENTIFIER=payments\n" +
+		"CODE_FILE=api/pay.go\n" +
+		"CODE_LINE=30\n" +
+		"LIVES=true\n" +
+		"PRIVATE=1\n" +
+		"FIELD_MESSAGE=user\n" +
+		"ORDER_ID=A1\n"
+	if got := readPacket(t, conn); got != want {
+		t.Errorf("datagram = %q, want %q", got, want)
+	}
+}
+
+func TestJournaldMultilineValue(t *testing.T) {
+	j := &JournaldSink{identifier: "app"}
+
+	stack := "main.main\n\tmain.go:1"
+	got := string(j.format(Entry{Level: InfoLevel, Message: "m", Stack: stack}))
+
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(stack)))
+	want := "STACKTRACE\n" + string(size[:]) + stack + "\n"
+	if !strings.Contains(got, want) {
+		t.Errorf("format() = %q, want the stack in binary form %q", got, want)
+	}
+}
+
+func TestJournaldFieldName(t *testing.T) {
+	tests := map[string]string{
+		"user_id":               "USER_ID",
+		"http.status":           "HTTP_STATUS",
+		"_x":                    "X",
+		"1st":                   "ST",
+		"PRIORITY":              "FIELD_PRIORITY",
+		"___":                   "",
+		strings.Repeat("a", 70): strings.Repeat("A", 64),
+	}
+	for key, want := range tests {
+		if got := journalFieldName(key); got != want {
+			t.Errorf("journalFieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestJournaldSinkReconnectBackoff(t *testing.T) {
+	conn, path := listenUnixgram(t)
+
+	sink, err := NewJournaldSink(path, "app")
+	if err != nil {
+		t.Fatalf("NewJournaldSink() error = %v", err)
+	}
+	defer sink.Close()
+
+	entry := Entry{Time: time.Now(), Level: InfoLevel, Message: "hello"}
+
+	// journald goes away
+	_ = conn.Close()
+	_ = os.Remove(path)
+
+	if err := sink.WriteEntry(entry); err == nil || errors.Is(err, errReconnectPending) {
+		t.Fatalf("WriteEntry() error = %v, want the reconnect failure", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := sink.WriteEntry(entry); !errors.Is(err, errReconnectPending) {
+			t.Fatalf("WriteEntry() error = %v, want errReconnectPending", err)
+		}
+	}
+	if got := sink.Dropped(); got != 4 {
+		t.Errorf("Dropped() = %d, want 4", got)
+	}
+
+	// journald is back and the delay has passed
+	conn, _ = listenUnixgramAt(t, path)
+	sink.backoff.retryAt = time.Time{}
+	if err := sink.WriteEntry(entry); err != nil {
+		t.Fatalf("WriteEntry() after journald came back error = %v", err)
+	}
+	if got := readPacket(t, conn); !strings.HasPrefix(got, "MESSAGE=hello\n") {
+		t.Errorf("datagram = %q", got)
+	}
+	if sink.backoff.delay != 0 {
+		t.Errorf("delay = %s after reconnecting, want it reset", sink.backoff.delay)
+	}
+}
+
+func TestLoggerJournaldDroppedEntries(t *testing.T) {
+	conn, path := listenUnixgram(t)
+
+	l, err := NewWithConfig(Config{Output: "journald", JournaldSocket: path, Level: "info"})
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+	defer l.Close()
+
+	l.Info("hello", "order_id", "A1")
+	if got := readPacket(t, conn); !strings.Contains(got, "ORDER_ID=A1\n") {
+		t.Errorf("datagram = %q", got)
+	}
+
+	_ = conn.Close()
+	_ = os.Remove(path)
+	l.Info("lost")
+	l.Info("lost")
+
+	if got := DroppedEntries(l); got != 2 {
+		t.Errorf("DroppedEntries() = %d, want 2", got)
+	}
+}
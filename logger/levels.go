@@ -0,0 +1,106 @@
+package logger
+
+import "strings"
+
+// NameField is the field holding the name of a logger created with Named
+const NameField = "logger"
+
+// levelResolver resolves the level of named loggers from Config.Levels
+type levelResolver struct {
+	base    LogLevel
+	modules map[string]LogLevel
+}
+
+// newLevelResolver parses the "name=level" entries of Config.Levels
+func newLevelResolver(config Config) *levelResolver {
+	r := &levelResolver{
+		base:    normalizeLevel(config.Level),
+		modules: make(map[string]LogLevel, len(config.Levels)),
+	}
+
+	for _, entry := range config.Levels {
+		name, level, ok := strings.Cut(entry, "=")
+		name = strings.Trim(strings.TrimSpace(name), ".")
+		if !ok || name == "" {
+			// Malformed entries are rejected by Config.Validate
+			continue
+		}
+		r.modules[name] = normalizeLevel(level)
+	}
+
+	return r
+}
+
+// resolve returns the level for a logger name using the longest configured
+// prefix, matching whole segments: "payments" covers "payments.stripe" but
+// not "paymentsv2". Unnamed and unmatched loggers use the base level.
+func (r *levelResolver) resolve(name string) LogLevel {
+	for name != "" {
+		if level, ok := r.modules[name]; ok {
+			return level
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return r.base
+}
+
+// min returns the most verbose configured level, which the underlying
+// backend must let through so named loggers can go below the base level
+func (r *levelResolver) min() LogLevel {
+	min := r.base
+	for _, level := range r.modules {
+		if levelRank(level) < levelRank(min) {
+			min = level
+		}
+	}
+	return min
+}
+
+// levelEnabled reports whether entries at level pass a logger set to min
+func levelEnabled(min, level LogLevel) bool {
+	return levelRank(level) >= levelRank(min)
+}
+
+// levelRank orders levels from debug to fatal
+func levelRank(level LogLevel) int {
+	return parseSimpleLevel(string(level))
+}
+
+// validLevel reports whether level is a known level name
+func validLevel(level string) bool {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug", "info", "warn", "warning", "error", "fatal":
+		return true
+	default:
+		return false
+	}
+}
+
+// normalizeLevel converts a configured level to a LogLevel. Unknown levels
+// are treated as info, like the backends do.
+func normalizeLevel(level string) LogLevel {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return DebugLevel
+	case "warn", "warning":
+		return WarnLevel
+	case "error":
+		return ErrorLevel
+	case "fatal":
+		return FatalLevel
+	default:
+		return InfoLevel
+	}
+}
+
+// joinName appends name to the parent logger name with a dot
+func joinName(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -1,8 +1,15 @@
 package logger
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/saipulimdn/gopackkit/config"
 )
 
 // Logger interface defines common logging methods
@@ -14,6 +21,16 @@ type Logger interface {
 	Fatal(msg string, fields ...interface{})
 	WithField(key string, value interface{}) Logger
 	WithFields(fields map[string]interface{}) Logger
+	// Named returns a logger for a module. Names nest with dots, so
+	// Named("payments").Named("stripe") is "payments.stripe", and the level
+	// comes from Config.Levels.
+	Named(name string) Logger
+
+	// Sync flushes any buffered entries to the output
+	Sync() error
+	// Close flushes buffered entries and releases the output, e.g. an opened
+	// log file. It affects every logger derived from the same constructor call.
+	Close() error
 }
 
 // Config holds logger configuration
@@ -23,6 +40,70 @@ type Config struct {
 	Output   string `json:"output" yaml:"output" env:"LOG_OUTPUT" default:"stdout"`
 	Backend  string `json:"backend" yaml:"backend" env:"LOG_BACKEND" default:"logrus"`
 	Filename string `json:"filename" yaml:"filename" env:"LOG_FILENAME"`
+
+	// Levels overrides Level for named loggers, as "name=level" entries such
+	// as "payments=debug". The longest matching name prefix wins.
+	Levels []string `json:"levels" yaml:"levels" env:"LOG_LEVELS"`
+
+	// Redaction settings. An empty RedactKeys uses DefaultRedactKeys, and
+	// RedactPatterns are added to DefaultRedactPatterns.
+	RedactKeys       []string `json:"redact_keys" yaml:"redact_keys" env:"LOG_REDACT_KEYS"`
+	RedactPatterns   []string `json:"redact_patterns" yaml:"redact_patterns" env:"LOG_REDACT_PATTERNS"`
+	DisableRedaction bool     `json:"disable_redaction" yaml:"disable_redaction" env:"LOG_DISABLE_REDACTION" default:"false"`
+
+	// Sampling settings. When SampleInitial is positive, the first SampleInitial
+	// entries with the same level, logger name and message are logged per SampleInterval,
+	// then every SampleThereafter-th. Dedup logs the first entry and collapses
+	// the repeats within the interval into one entry with a "repeated" count.
+	SampleInitial    int           `json:"sample_initial" yaml:"sample_initial" env:"LOG_SAMPLE_INITIAL"`
+	SampleThereafter int           `json:"sample_thereafter" yaml:"sample_thereafter" env:"LOG_SAMPLE_THEREAFTER"`
+	SampleInterval   time.Duration `json:"sample_interval" yaml:"sample_interval" env:"LOG_SAMPLE_INTERVAL"`
+	Dedup            bool          `json:"dedup" yaml:"dedup" env:"LOG_DEDUP" default:"false"`
+
+	// Async mode hands entries to a background writer through a bounded ring
+	// buffer of BufferSize entries. DropPolicy decides what happens when it is full.
+	Async      bool   `json:"async" yaml:"async" env:"LOG_ASYNC" default:"false"`
+	BufferSize int    `json:"buffer_size" yaml:"buffer_size" env:"LOG_BUFFER_SIZE" default:"1024"`
+	DropPolicy string `json:"drop_policy" yaml:"drop_policy" env:"LOG_DROP_POLICY" default:"block"`
+
+	// DisableCaller omits the "dir/file.go:line" of the logging call.
+	// StackTrace attaches a stack trace to Error and Fatal entries.
+	DisableCaller bool `json:"disable_caller" yaml:"disable_caller" env:"LOG_DISABLE_CALLER" default:"false"`
+	StackTrace    bool `json:"stack_trace" yaml:"stack_trace" env:"LOG_STACK_TRACE" default:"false"`
+
+	// Settings for the "syslog" and "journald" outputs. AppName is used as
+	// the syslog APP-NAME and journald SYSLOG_IDENTIFIER.
+	AppName        string `json:"app_name" yaml:"app_name" env:"LOG_APP_NAME"`
+	SyslogNetwork  string `json:"syslog_network" yaml:"syslog_network" env:"LOG_SYSLOG_NETWORK"`
+	SyslogAddress  string `json:"syslog_address" yaml:"syslog_address" env:"LOG_SYSLOG_ADDRESS"`
+	SyslogFacility string `json:"syslog_facility" yaml:"syslog_facility" env:"LOG_SYSLOG_FACILITY" default:"user"`
+	JournaldSocket string `json:"journald_socket" yaml:"journald_socket" env:"LOG_JOURNALD_SOCKET"`
+
+	// HTTP log shipping, in addition to Output. ShipTo is "loki" or
+	// "elasticsearch" and ShipURL the push or _bulk endpoint. ShipLabels
+	// lists the fields used as Loki stream labels, next to app and level.
+	ShipTo            string            `json:"ship_to" yaml:"ship_to" env:"LOG_SHIP_TO"`
+	ShipURL           string            `json:"ship_url" yaml:"ship_url" env:"LOG_SHIP_URL"`
+	ShipIndex         string            `json:"ship_index" yaml:"ship_index" env:"LOG_SHIP_INDEX"`
+	ShipLabels        []string          `json:"ship_labels" yaml:"ship_labels" env:"LOG_SHIP_LABELS"`
+	ShipHeaders       map[string]string `json:"ship_headers" yaml:"ship_headers" env:"LOG_SHIP_HEADERS"`
+	ShipBatchSize     int               `json:"ship_batch_size" yaml:"ship_batch_size" env:"LOG_SHIP_BATCH_SIZE" default:"500"`
+	ShipFlushInterval time.Duration     `json:"ship_flush_interval" yaml:"ship_flush_interval" env:"LOG_SHIP_FLUSH_INTERVAL"`
+	ShipSpillDir      string            `json:"ship_spill_dir" yaml:"ship_spill_dir" env:"LOG_SHIP_SPILL_DIR"`
+
+	// Hooks are fired asynchronously, each from its own queue of
+	// HookBufferSize entries. HookRateLimit caps each hook to that many
+	// entries per second with bursts of HookBurst; zero means unlimited.
+	Hooks          []Hook  `json:"-" yaml:"-" env:"-"`
+	HookBufferSize int     `json:"hook_buffer_size" yaml:"hook_buffer_size" env:"LOG_HOOK_BUFFER_SIZE" default:"256"`
+	HookRateLimit  float64 `json:"hook_rate_limit" yaml:"hook_rate_limit" env:"LOG_HOOK_RATE_LIMIT"`
+	HookBurst      int     `json:"hook_burst" yaml:"hook_burst" env:"LOG_HOOK_BURST" default:"10"`
+
+	// ExitFunc is called after a Fatal entry instead of os.Exit, once the
+	// logger is flushed and the OnShutdown cleanups have run, or once
+	// ShutdownTimeout (10s by default) has elapsed for both together.
+	ExitFunc        func(code int) `json:"-" yaml:"-" env:"-"`
+	ShutdownTimeout time.Duration  `json:"shutdown_timeout" yaml:"shutdown_timeout" env:"LOG_SHUTDOWN_TIMEOUT"`
 }
 
 // LogLevel represents log levels
@@ -40,8 +121,10 @@ const (
 type LogFormat string
 
 const (
-	TextFormat LogFormat = "text"
-	JSONFormat LogFormat = "json"
+	TextFormat    LogFormat = "text"
+	JSONFormat    LogFormat = "json"
+	LogfmtFormat  LogFormat = "logfmt"
+	ConsoleFormat LogFormat = "console"
 )
 
 // LogBackend represents logging backends
@@ -50,20 +133,44 @@ type LogBackend string
 const (
 	LogrusBackend LogBackend = "logrus"
 	ZapBackend    LogBackend = "zap"
+	SimpleBackend LogBackend = "simple"
+)
+
+// DropPolicy represents what an async logger does when its buffer is full
+type DropPolicy string
+
+const (
+	BlockPolicy      DropPolicy = "block"
+	DropNewestPolicy DropPolicy = "drop-newest"
+	DropOldestPolicy DropPolicy = "drop-oldest"
 )
 
-// New creates a logger with default configuration
-func New() Logger {
-	return NewWithConfig(Config{
+// New creates a logger with default configuration. It panics when opts make
+// the configuration invalid; use NewWithConfig to handle the error instead.
+func New(opts ...Option) Logger {
+	l, err := NewWithConfig(Config{
 		Level:   "info",
 		Format:  "text",
 		Output:  "stdout",
 		Backend: "logrus",
-	})
+	}, opts...)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create logger: %v", err))
+	}
+	return l
 }
 
-// NewWithConfig creates a logger with custom configuration
-func NewWithConfig(config Config) Logger {
+// NewWithConfig creates a logger with custom configuration. It fails when
+// the configuration is invalid or the output can't be opened.
+func NewWithConfig(config Config, opts ...Option) (Logger, error) {
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid logger config: %w", err)
+	}
+
 	switch LogBackend(config.Backend) {
 	case ZapBackend:
 		return newZapLogger(config)
@@ -74,22 +181,111 @@ func NewWithConfig(config Config) Logger {
 	}
 }
 
+// NewFromEnv creates a logger configured from the LOG_* environment
+// variables, using the default tag values for the unset ones
+func NewFromEnv(opts ...Option) (Logger, error) {
+	var cfg Config
+	if err := config.Load(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to load logger config: %w", err)
+	}
+	return NewWithConfig(cfg, opts...)
+}
+
+// Validate reports every invalid setting. Empty values are valid and use
+// the defaults.
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.Level != "" && !validLevel(c.Level) {
+		errs = append(errs, fmt.Errorf("unknown level: %s", c.Level))
+	}
+	for _, entry := range c.Levels {
+		name, level, ok := strings.Cut(entry, "=")
+		if !ok || strings.Trim(strings.TrimSpace(name), ".") == "" || !validLevel(level) {
+			errs = append(errs, fmt.Errorf("invalid levels entry, expected name=level: %s", entry))
+		}
+	}
+
+	switch LogFormat(c.Format) {
+	case "", TextFormat, JSONFormat, LogfmtFormat, ConsoleFormat:
+	default:
+		errs = append(errs, fmt.Errorf("unknown format: %s", c.Format))
+	}
+
+	switch LogBackend(c.Backend) {
+	case "", LogrusBackend, ZapBackend, SimpleBackend:
+	default:
+		errs = append(errs, fmt.Errorf("unknown backend: %s", c.Backend))
+	}
+
+	switch c.Output {
+	case "", "stdout", "stderr", "syslog", "journald":
+	case "file":
+		if c.Filename == "" {
+			errs = append(errs, errors.New("filename is required for file output"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown output: %s", c.Output))
+	}
+
+	if c.DropPolicy != "" {
+		switch DropPolicy(strings.ReplaceAll(strings.ToLower(c.DropPolicy), "_", "-")) {
+		case BlockPolicy, DropNewestPolicy, DropOldestPolicy:
+		default:
+			errs = append(errs, fmt.Errorf("unknown drop policy: %s", c.DropPolicy))
+		}
+	}
+
+	for _, expr := range c.RedactPatterns {
+		if _, err := regexp.Compile(expr); err != nil {
+			errs = append(errs, fmt.Errorf("invalid redact pattern %q: %w", expr, err))
+		}
+	}
+
+	if c.SyslogFacility != "" {
+		if _, ok := syslogFacilities[strings.ToLower(c.SyslogFacility)]; !ok {
+			errs = append(errs, fmt.Errorf("unknown syslog facility: %s", c.SyslogFacility))
+		}
+	}
+
+	switch ShipperKind(c.ShipTo) {
+	case "":
+	case LokiShipper, ElasticsearchShipper:
+		if c.ShipURL == "" {
+			errs = append(errs, errors.New("ship URL is required when shipping logs"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown log shipper: %s", c.ShipTo))
+	}
+
+	return errors.Join(errs...)
+}
+
+// DroppedEntries returns how many entries the async buffer, the syslog and
+// journald outputs, the log shipper and the hooks of l have discarded. It returns 0
+// for loggers using none.
+func DroppedEntries(l Logger) uint64 {
+	if d, ok := l.(interface{ Dropped() uint64 }); ok {
+		return d.Dropped()
+	}
+	return 0
+}
+
 // getWriter returns the appropriate writer based on output configuration
-func getWriter(config Config) io.Writer {
+func getWriter(config Config) (io.Writer, error) {
 	switch config.Output {
 	case "stderr":
-		return os.Stderr
+		return os.Stderr, nil
+	case "syslog", "journald":
+		// Entries are delivered by the output sink instead
+		return io.Discard, nil
 	case "file":
-		if config.Filename != "" {
-			file, err := os.OpenFile(config.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-			if err != nil {
-				// Fallback to stdout if file can't be opened
-				return os.Stdout
-			}
-			return file
+		file, err := os.OpenFile(config.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
 		}
-		return os.Stdout
+		return file, nil
 	default:
-		return os.Stdout
+		return os.Stdout, nil
 	}
 }
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newRawTestLogger creates a logger writing to a temporary file and returns
+// a function reading back what was written so far
+func newRawTestLogger(t *testing.T, config Config) (Logger, func() string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.log")
+	config.Output = "file"
+	config.Filename = path
+	if config.Format == "" {
+		config.Format = "json"
+	}
+	if config.Level == "" {
+		config.Level = "debug"
+	}
+	if config.Backend == "" {
+		config.Backend = "simple"
+	}
+
+	l, err := NewWithConfig(config)
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	return l, func() string {
+		t.Helper()
+		if err := l.Sync(); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read log file: %v", err)
+		}
+		return string(data)
+	}
+}
+
+// newTestLogger creates a JSON logger writing to a temporary file and
+// returns a function decoding the entries written so far
+func newTestLogger(t *testing.T, config Config) (Logger, func() []map[string]interface{}) {
+	t.Helper()
+
+	l, output := newRawTestLogger(t, config)
+	return l, func() []map[string]interface{} {
+		t.Helper()
+
+		var entries []map[string]interface{}
+		scanner := bufio.NewScanner(strings.NewReader(output()))
+		for scanner.Scan() {
+			var entry map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+			}
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+}
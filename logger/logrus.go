@@ -8,88 +8,213 @@ import (
 
 // logrusLogger wraps logrus.Logger to implement our Logger interface
 type logrusLogger struct {
-	logger *logrus.Logger
-	entry  *logrus.Entry
+	logger   *logrus.Logger
+	entry    *logrus.Entry
+	name     string
+	level    LogLevel
+	pipeline *pipeline
 }
 
 // newLogrusLogger creates a new logrus-based logger
-func newLogrusLogger(config Config) Logger {
+func newLogrusLogger(config Config) (Logger, error) {
+	pipeline, err := newPipeline(config)
+	if err != nil {
+		return nil, err
+	}
 	logger := logrus.New()
 
-	// Set level
-	logger.SetLevel(parseLogrusLevel(config.Level))
+	// Set level. Levels are checked per logger by enabled, so logrus only
+	// needs to let through the most verbose one.
+	logger.SetLevel(parseLogrusLevel(string(pipeline.levels.min())))
 
 	// Set formatter
-	if config.Format == string(JSONFormat) {
+	switch LogFormat(config.Format) {
+	case JSONFormat:
 		logger.SetFormatter(&logrus.JSONFormatter{})
-	} else {
-		logger.SetFormatter(&logrus.TextFormatter{
-			FullTimestamp: true,
+	case LogfmtFormat, ConsoleFormat:
+		logger.SetFormatter(entryFormatterAdapter{
+			format: entryFormatter(config.Format, pipeline.out.color),
 		})
+	default:
+		logger.SetFormatter(textFieldsFormatter{&logrus.TextFormatter{
+			FullTimestamp: true,
+		}})
 	}
 
 	// Set output
-	logger.SetOutput(getWriter(config))
+	logger.SetOutput(pipeline.out)
+	logger.ExitFunc = pipeline.exit
 
 	return &logrusLogger{
-		logger: logger,
-		entry:  logger.WithFields(logrus.Fields{}),
-	}
+		logger:   logger,
+		entry:    logger.WithFields(logrus.Fields{}),
+		level:    pipeline.levels.resolve(""),
+		pipeline: pipeline,
+	}, nil
 }
 
 func (l *logrusLogger) Debug(msg string, fields ...interface{}) {
-	l.logWithFields(l.entry.Debug, msg, fields...)
+	l.pipeline.log(l, DebugLevel, msg, fields)
 }
 
 func (l *logrusLogger) Info(msg string, fields ...interface{}) {
-	l.logWithFields(l.entry.Info, msg, fields...)
+	l.pipeline.log(l, InfoLevel, msg, fields)
 }
 
 func (l *logrusLogger) Warn(msg string, fields ...interface{}) {
-	l.logWithFields(l.entry.Warn, msg, fields...)
+	l.pipeline.log(l, WarnLevel, msg, fields)
 }
 
 func (l *logrusLogger) Error(msg string, fields ...interface{}) {
-	l.logWithFields(l.entry.Error, msg, fields...)
+	l.pipeline.log(l, ErrorLevel, msg, fields)
 }
 
 func (l *logrusLogger) Fatal(msg string, fields ...interface{}) {
-	l.logWithFields(l.entry.Fatal, msg, fields...)
+	l.pipeline.log(l, FatalLevel, msg, fields)
 }
 
 func (l *logrusLogger) WithField(key string, value interface{}) Logger {
 	return &logrusLogger{
-		logger: l.logger,
-		entry:  l.entry.WithField(key, value),
+		logger:   l.logger,
+		entry:    l.entry.WithField(key, l.pipeline.field(key, value)),
+		name:     l.name,
+		level:    l.level,
+		pipeline: l.pipeline,
 	}
 }
 
 func (l *logrusLogger) WithFields(fields map[string]interface{}) Logger {
 	return &logrusLogger{
-		logger: l.logger,
-		entry:  l.entry.WithFields(fields),
+		logger:   l.logger,
+		entry:    l.entry.WithFields(l.pipeline.fields(fields)),
+		name:     l.name,
+		level:    l.level,
+		pipeline: l.pipeline,
 	}
 }
 
-// logWithFields handles the key-value pairs and logs the message
-func (l *logrusLogger) logWithFields(logFunc func(...interface{}), msg string, fields ...interface{}) {
-	if len(fields) == 0 {
-		logFunc(msg)
-		return
+func (l *logrusLogger) Named(name string) Logger {
+	if name == "" {
+		return l
 	}
+	name = joinName(l.name, name)
+
+	return &logrusLogger{
+		logger:   l.logger,
+		entry:    l.entry.WithField(NameField, name),
+		name:     name,
+		level:    l.pipeline.levels.resolve(name),
+		pipeline: l.pipeline,
+	}
+}
+
+func (l *logrusLogger) Sync() error {
+	return l.pipeline.sync()
+}
 
+func (l *logrusLogger) Close() error {
+	return l.pipeline.close()
+}
+
+// Dropped returns the number of entries discarded by the async buffer and the sinks
+func (l *logrusLogger) Dropped() uint64 {
+	return l.pipeline.dropped()
+}
+
+func (l *logrusLogger) context() map[string]interface{} {
+	return l.entry.Data
+}
+
+func (l *logrusLogger) enabled(level LogLevel) bool {
+	return levelEnabled(l.level, level)
+}
+
+// write handles the key-value pairs and logs the message
+func (l *logrusLogger) write(level LogLevel, msg string, fields []interface{}, site callSite) {
 	// Convert fields to logrus.Fields
-	logrusFields := make(logrus.Fields)
+	logrusFields := make(logrus.Fields, len(fields)/2+2)
 	for i := 0; i < len(fields)-1; i += 2 {
 		if key, ok := fields[i].(string); ok && i+1 < len(fields) {
 			logrusFields[key] = fields[i+1]
 		}
 	}
 
+	// logrus' own ReportCaller would point at this file, so the call site
+	// resolved by the pipeline is added as a regular field instead
+	if caller := site.caller(); caller != "" {
+		logrusFields["caller"] = caller
+	}
+	if site.stack != "" {
+		logrusFields["stacktrace"] = site.stack
+	}
+
+	entry := l.entry
 	if len(logrusFields) > 0 {
-		l.entry.WithFields(logrusFields).Log(l.logger.Level, msg)
-	} else {
-		logFunc(msg)
+		entry = entry.WithFields(logrusFields)
+	}
+
+	entry.Log(parseLogrusLevel(string(level)), msg)
+
+	// Entry.Log doesn't exit on its own, unlike Entry.Fatal
+	if level == FatalLevel {
+		l.logger.Exit(1)
+	}
+}
+
+// entryFormatterAdapter is a logrus.Formatter rendering entries with the
+// formatters shared by all backends
+type entryFormatterAdapter struct {
+	format func(Entry) []byte
+}
+
+func (f entryFormatterAdapter) Format(e *logrus.Entry) ([]byte, error) {
+	entry := Entry{
+		Time:    e.Time,
+		Level:   logrusToLevel(e.Level),
+		Message: e.Message,
+		Fields:  make(map[string]interface{}, len(e.Data)),
+	}
+
+	// write passes the call site as regular fields
+	for key, value := range e.Data {
+		switch key {
+		case "caller":
+			entry.Caller = fieldString(value)
+		case "stacktrace":
+			entry.Stack = fieldString(value)
+		default:
+			entry.Fields[key] = value
+		}
+	}
+
+	return append(f.format(entry), '\n'), nil
+}
+
+// textFieldsFormatter splits error fields like the other text formats
+// before handing the entry to the logrus formatter
+type textFieldsFormatter struct {
+	logrus.Formatter
+}
+
+func (f textFieldsFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	entry := *e
+	entry.Data = textFields(e.Data)
+	return f.Formatter.Format(&entry)
+}
+
+// logrusToLevel converts a logrus level to a LogLevel
+func logrusToLevel(level logrus.Level) LogLevel {
+	switch level {
+	case logrus.TraceLevel, logrus.DebugLevel:
+		return DebugLevel
+	case logrus.InfoLevel:
+		return InfoLevel
+	case logrus.WarnLevel:
+		return WarnLevel
+	case logrus.ErrorLevel:
+		return ErrorLevel
+	default:
+		return FatalLevel
 	}
 }
 
@@ -0,0 +1,321 @@
+// Package logtest provides an in-memory logger.Logger that records entries so
+// tests can assert on what was logged.
+package logtest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saipulimdn/gopackkit/logger"
+)
+
+// Entry is a recorded log entry
+type Entry struct {
+	Time    time.Time
+	Level   logger.LogLevel
+	Message string
+	// Fields holds the fields from WithField/WithFields merged with the
+	// key-value pairs passed to the logging call
+	Fields map[string]interface{}
+}
+
+// Field returns the value of a field and whether it is present
+func (e Entry) Field(key string) (interface{}, bool) {
+	value, ok := e.Fields[key]
+	return value, ok
+}
+
+// String formats the entry like "[INFO] msg k=v" with sorted fields
+func (e Entry) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s", strings.ToUpper(string(e.Level)), e.Message)
+	for _, key := range sortedKeys(e.Fields) {
+		fmt.Fprintf(&b, " %s=%v", key, e.Fields[key])
+	}
+	return b.String()
+}
+
+// Entries is a list of recorded entries supporting chained filters
+type Entries []Entry
+
+// Filter returns the entries for which match returns true
+func (e Entries) Filter(match func(Entry) bool) Entries {
+	var filtered Entries
+	for _, entry := range e {
+		if match(entry) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// FilterLevel returns the entries logged at level
+func (e Entries) FilterLevel(level logger.LogLevel) Entries {
+	return e.Filter(func(entry Entry) bool {
+		return entry.Level == level
+	})
+}
+
+// FilterMessage returns the entries with exactly the given message
+func (e Entries) FilterMessage(msg string) Entries {
+	return e.Filter(func(entry Entry) bool {
+		return entry.Message == msg
+	})
+}
+
+// FilterMessageContains returns the entries whose message contains substr
+func (e Entries) FilterMessageContains(substr string) Entries {
+	return e.Filter(func(entry Entry) bool {
+		return strings.Contains(entry.Message, substr)
+	})
+}
+
+// FilterField returns the entries having key set to value. Values match when
+// they are deeply equal or format identically, so 42 matches int64(42).
+func (e Entries) FilterField(key string, value interface{}) Entries {
+	return e.Filter(func(entry Entry) bool {
+		actual, ok := entry.Fields[key]
+		return ok && valuesEqual(actual, value)
+	})
+}
+
+// FilterFieldKey returns the entries that have a field named key
+func (e Entries) FilterFieldKey(key string) Entries {
+	return e.Filter(func(entry Entry) bool {
+		_, ok := entry.Fields[key]
+		return ok
+	})
+}
+
+// FilterLogger returns the entries logged by the named logger name
+func (e Entries) FilterLogger(name string) Entries {
+	return e.FilterField(logger.NameField, name)
+}
+
+// Len returns the number of entries
+func (e Entries) Len() int {
+	return len(e)
+}
+
+// store holds the entries shared by a Recorder and its derived loggers
+type store struct {
+	mu      sync.Mutex
+	entries Entries
+}
+
+var _ logger.Logger = (*Recorder)(nil)
+
+// Recorder is a logger.Logger that keeps every entry in memory. All levels
+// are recorded, and Fatal records the entry without exiting.
+type Recorder struct {
+	store  *store
+	name   string
+	fields map[string]interface{}
+}
+
+// New creates an empty Recorder
+func New() *Recorder {
+	return &Recorder{
+		store:  &store{},
+		fields: make(map[string]interface{}),
+	}
+}
+
+func (r *Recorder) Debug(msg string, fields ...interface{}) {
+	r.record(logger.DebugLevel, msg, fields)
+}
+
+func (r *Recorder) Info(msg string, fields ...interface{}) {
+	r.record(logger.InfoLevel, msg, fields)
+}
+
+func (r *Recorder) Warn(msg string, fields ...interface{}) {
+	r.record(logger.WarnLevel, msg, fields)
+}
+
+func (r *Recorder) Error(msg string, fields ...interface{}) {
+	r.record(logger.ErrorLevel, msg, fields)
+}
+
+// Fatal records the entry at FatalLevel. Unlike the real backends it does not exit.
+func (r *Recorder) Fatal(msg string, fields ...interface{}) {
+	r.record(logger.FatalLevel, msg, fields)
+}
+
+func (r *Recorder) WithField(key string, value interface{}) logger.Logger {
+	return r.WithFields(map[string]interface{}{key: value})
+}
+
+func (r *Recorder) WithFields(fields map[string]interface{}) logger.Logger {
+	newFields := make(map[string]interface{}, len(r.fields)+len(fields))
+	for k, v := range r.fields {
+		newFields[k] = v
+	}
+	for k, v := range fields {
+		newFields[k] = v
+	}
+
+	return &Recorder{
+		store:  r.store,
+		name:   r.name,
+		fields: newFields,
+	}
+}
+
+// Named records the dotted logger name in the logger.NameField field, like
+// the real backends. Config.Levels doesn't apply since all levels are recorded.
+func (r *Recorder) Named(name string) logger.Logger {
+	if name == "" {
+		return r
+	}
+	if r.name != "" {
+		name = r.name + "." + name
+	}
+
+	recorder := r.WithField(logger.NameField, name).(*Recorder)
+	recorder.name = name
+	return recorder
+}
+
+// Sync is a no-op
+func (r *Recorder) Sync() error {
+	return nil
+}
+
+// Close is a no-op
+func (r *Recorder) Close() error {
+	return nil
+}
+
+// All returns a copy of every recorded entry, including those of derived loggers
+func (r *Recorder) All() Entries {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entries := make(Entries, len(r.store.entries))
+	copy(entries, r.store.entries)
+	return entries
+}
+
+// Len returns the number of recorded entries
+func (r *Recorder) Len() int {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return len(r.store.entries)
+}
+
+// Reset discards all recorded entries
+func (r *Recorder) Reset() {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.entries = nil
+}
+
+// AssertLogged fails the test unless an entry with the given level, message
+// and key-value pairs was recorded. It returns the first matching entry.
+func (r *Recorder) AssertLogged(t testing.TB, level logger.LogLevel, msg string, keysAndValues ...interface{}) Entry {
+	t.Helper()
+
+	matches := r.match(level, msg, keysAndValues)
+	if len(matches) == 0 {
+		t.Errorf("expected entry %s, got:\n%s", describe(level, msg, keysAndValues), r.dump())
+		return Entry{}
+	}
+	return matches[0]
+}
+
+// AssertNotLogged fails the test if an entry with the given level, message
+// and key-value pairs was recorded
+func (r *Recorder) AssertNotLogged(t testing.TB, level logger.LogLevel, msg string, keysAndValues ...interface{}) {
+	t.Helper()
+
+	if matches := r.match(level, msg, keysAndValues); len(matches) > 0 {
+		t.Errorf("unexpected entry %s, got:\n%s", describe(level, msg, keysAndValues), r.dump())
+	}
+}
+
+// AssertCount fails the test unless exactly n entries were recorded at level
+func (r *Recorder) AssertCount(t testing.TB, level logger.LogLevel, n int) {
+	t.Helper()
+
+	if got := r.All().FilterLevel(level).Len(); got != n {
+		t.Errorf("expected %d %s entries, got %d:\n%s", n, level, got, r.dump())
+	}
+}
+
+func (r *Recorder) record(level logger.LogLevel, msg string, fields []interface{}) {
+	allFields := make(map[string]interface{}, len(r.fields)+len(fields)/2)
+	for k, v := range r.fields {
+		allFields[k] = v
+	}
+	for i := 0; i < len(fields)-1; i += 2 {
+		if key, ok := fields[i].(string); ok {
+			allFields[key] = fields[i+1]
+		}
+	}
+
+	entry := Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: msg,
+		Fields:  allFields,
+	}
+
+	r.store.mu.Lock()
+	r.store.entries = append(r.store.entries, entry)
+	r.store.mu.Unlock()
+}
+
+func (r *Recorder) match(level logger.LogLevel, msg string, keysAndValues []interface{}) Entries {
+	matches := r.All().FilterLevel(level).FilterMessage(msg)
+	for i := 0; i < len(keysAndValues)-1; i += 2 {
+		if key, ok := keysAndValues[i].(string); ok {
+			matches = matches.FilterField(key, keysAndValues[i+1])
+		}
+	}
+	return matches
+}
+
+func (r *Recorder) dump() string {
+	entries := r.All()
+	if len(entries) == 0 {
+		return "  (no entries)"
+	}
+
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = "  " + entry.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func describe(level logger.LogLevel, msg string, keysAndValues []interface{}) string {
+	fields := make(map[string]interface{}, len(keysAndValues)/2)
+	for i := 0; i < len(keysAndValues)-1; i += 2 {
+		if key, ok := keysAndValues[i].(string); ok {
+			fields[key] = keysAndValues[i+1]
+		}
+	}
+	return Entry{Level: level, Message: msg, Fields: fields}.String()
+}
+
+func valuesEqual(actual, expected interface{}) bool {
+	if reflect.DeepEqual(actual, expected) {
+		return true
+	}
+	return fmt.Sprint(actual) == fmt.Sprint(expected)
+}
+
+func sortedKeys(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
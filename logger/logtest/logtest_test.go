@@ -0,0 +1,202 @@
+package logtest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/saipulimdn/gopackkit/logger"
+)
+
+// fakeT records the failures reported by the assertion helpers
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecorderRecordsAllLevels(t *testing.T) {
+	rec := New()
+
+	rec.Debug("debug")
+	rec.Info("info")
+	rec.Warn("warn")
+	rec.Error("error")
+	rec.Fatal("fatal")
+
+	entries := rec.All()
+	if entries.Len() != 5 || rec.Len() != 5 {
+		t.Fatalf("recorded %d entries, want 5", entries.Len())
+	}
+	levels := []logger.LogLevel{logger.DebugLevel, logger.InfoLevel, logger.WarnLevel, logger.ErrorLevel, logger.FatalLevel}
+	for i, level := range levels {
+		if entries[i].Level != level || entries[i].Message != string(level) {
+			t.Errorf("entry %d = %s, want level %s", i, entries[i], level)
+		}
+		if entries[i].Time.IsZero() {
+			t.Errorf("entry %d has no time", i)
+		}
+	}
+}
+
+func TestRecorderFields(t *testing.T) {
+	rec := New()
+
+	child := rec.WithField("request_id", "r1").WithFields(map[string]interface{}{"user": "jane"})
+	child.Info("login", "ok", true, "user", "john", 42, "ignored", "dangling")
+
+	entry := rec.All()[0]
+	want := map[string]interface{}{"request_id": "r1", "user": "john", "ok": true}
+	if len(entry.Fields) != len(want) {
+		t.Fatalf("fields = %v, want %v", entry.Fields, want)
+	}
+	for key, value := range want {
+		if got, ok := entry.Field(key); !ok || got != value {
+			t.Errorf("field %s = %v, want %v", key, got, value)
+		}
+	}
+
+	// The parent is not affected by the fields of derived loggers
+	rec.Info("plain")
+	if fields := rec.All()[1].Fields; len(fields) != 0 {
+		t.Errorf("parent entry fields = %v, want none", fields)
+	}
+}
+
+func TestRecorderNamed(t *testing.T) {
+	rec := New()
+
+	rec.Named("payments").Named("stripe").Info("charged")
+	rec.Named("").Info("unnamed")
+
+	rec.AssertLogged(t, logger.InfoLevel, "charged", logger.NameField, "payments.stripe")
+	if got := rec.All().FilterLogger("payments.stripe").Len(); got != 1 {
+		t.Errorf("FilterLogger() matched %d entries, want 1", got)
+	}
+	if _, ok := rec.All()[1].Field(logger.NameField); ok {
+		t.Error("Named(\"\") added a logger name")
+	}
+}
+
+func TestEntriesFilters(t *testing.T) {
+	rec := New()
+	rec.Info("user created", "id", 42)
+	rec.Info("user deleted", "id", int64(7))
+	rec.Error("user create failed", "err", errors.New("boom"))
+
+	all := rec.All()
+	tests := []struct {
+		name string
+		got  Entries
+		want int
+	}{
+		{"level", all.FilterLevel(logger.InfoLevel), 2},
+		{"message", all.FilterMessage("user created"), 1},
+		{"message contains", all.FilterMessageContains("user"), 3},
+		{"field", all.FilterField("id", 42), 1},
+		{"field across int types", all.FilterField("id", 7), 1},
+		{"field key", all.FilterFieldKey("err"), 1},
+		{"chained", all.FilterLevel(logger.InfoLevel).FilterMessageContains("deleted"), 1},
+		{"no match", all.FilterField("id", 1), 0},
+	}
+	for _, tt := range tests {
+		if tt.got.Len() != tt.want {
+			t.Errorf("%s: got %d entries, want %d", tt.name, tt.got.Len(), tt.want)
+		}
+	}
+}
+
+func TestEntryString(t *testing.T) {
+	entry := Entry{Level: logger.InfoLevel, Message: "msg", Fields: map[string]interface{}{"b": 2, "a": 1}}
+	if got, want := entry.String(), "[INFO] msg a=1 b=2"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestRecorderReset(t *testing.T) {
+	rec := New()
+	rec.WithField("k", "v").Info("msg")
+	rec.Reset()
+
+	if rec.Len() != 0 {
+		t.Errorf("Len() after Reset = %d, want 0", rec.Len())
+	}
+	if err := rec.Sync(); err != nil {
+		t.Errorf("Sync() error = %v", err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	rec := New()
+	rec.Info("paid", "amount", 10)
+	rec.Error("failed", "code", "E1")
+
+	t.Run("passing", func(t *testing.T) {
+		ft := &fakeT{TB: t}
+		entry := rec.AssertLogged(ft, logger.InfoLevel, "paid", "amount", 10)
+		rec.AssertNotLogged(ft, logger.InfoLevel, "paid", "amount", 11)
+		rec.AssertCount(ft, logger.ErrorLevel, 1)
+
+		if len(ft.errors) != 0 {
+			t.Errorf("assertions failed: %v", ft.errors)
+		}
+		if entry.Message != "paid" {
+			t.Errorf("AssertLogged() returned %s", entry)
+		}
+	})
+
+	t.Run("failing", func(t *testing.T) {
+		ft := &fakeT{TB: t}
+		entry := rec.AssertLogged(ft, logger.InfoLevel, "paid", "amount", 11)
+		rec.AssertNotLogged(ft, logger.ErrorLevel, "failed")
+		rec.AssertCount(ft, logger.InfoLevel, 2)
+
+		if len(ft.errors) != 3 {
+			t.Fatalf("got %d failures, want 3: %v", len(ft.errors), ft.errors)
+		}
+		if entry.Message != "" {
+			t.Errorf("AssertLogged() returned %s for a missing entry", entry)
+		}
+		// Failures list what was recorded to ease debugging
+		if !strings.Contains(ft.errors[0], "[INFO] paid amount=11") || !strings.Contains(ft.errors[0], "[ERROR] failed code=E1") {
+			t.Errorf("failure message = %q", ft.errors[0])
+		}
+	})
+
+	t.Run("empty recorder", func(t *testing.T) {
+		ft := &fakeT{TB: t}
+		New().AssertLogged(ft, logger.InfoLevel, "paid")
+		if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "(no entries)") {
+			t.Errorf("failures = %v", ft.errors)
+		}
+	})
+}
+
+func TestRecorderConcurrent(t *testing.T) {
+	rec := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l := rec.WithField("worker", i)
+			for j := 0; j < 100; j++ {
+				l.Info("tick")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	rec.AssertCount(t, logger.InfoLevel, 1000)
+}
@@ -0,0 +1,247 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxRequestIDLength bounds request IDs accepted from clients
+const maxRequestIDLength = 128
+
+// MiddlewareConfig holds the settings of the access-log middleware
+type MiddlewareConfig struct {
+	// Message is the message of access log entries
+	Message string
+	// SkipPaths are request paths that are not logged, e.g. health checks.
+	// A path ending in "*" matches every path with that prefix.
+	SkipPaths []string
+	// SlowThreshold logs requests taking longer at warn level with
+	// slow=true. Zero disables it.
+	SlowThreshold time.Duration
+	// RequestIDHeader is read for an incoming request ID and set on the
+	// response. Requests without a valid one get a random ID.
+	RequestIDHeader string
+	// TrustProxy takes the client IP from X-Forwarded-For or X-Real-IP.
+	// Only enable it behind a proxy that sets these headers.
+	TrustProxy bool
+}
+
+// DefaultMiddlewareConfig returns the default middleware configuration
+func DefaultMiddlewareConfig() MiddlewareConfig {
+	return MiddlewareConfig{
+		Message:         "http request",
+		RequestIDHeader: "X-Request-ID",
+	}
+}
+
+// Middleware returns net/http middleware writing an access log entry per
+// request with the default configuration
+func Middleware(l Logger) func(http.Handler) http.Handler {
+	return MiddlewareWithConfig(l, DefaultMiddlewareConfig())
+}
+
+// MiddlewareWithConfig returns net/http middleware writing an access log
+// entry per request with method, path, status, bytes, latency, client IP
+// and request ID.
+//
+// Handlers get a request-scoped logger carrying request_id, method and path
+// through FromContext(r.Context()). Server errors are logged at error level,
+// slow requests at warn level and everything else at info level.
+func MiddlewareWithConfig(l Logger, config MiddlewareConfig) func(http.Handler) http.Handler {
+	if config.Message == "" {
+		config.Message = "http request"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := ""
+			if config.RequestIDHeader != "" {
+				requestID = validRequestID(r.Header.Get(config.RequestIDHeader))
+				if requestID == "" {
+					requestID = newRequestID()
+				}
+				w.Header().Set(config.RequestIDHeader, requestID)
+			}
+
+			fields := map[string]interface{}{
+				"method": r.Method,
+				"path":   r.URL.Path,
+			}
+			if requestID != "" {
+				fields["request_id"] = requestID
+			}
+			reqLogger := l.WithFields(fields)
+
+			ctx := NewContext(r.Context(), reqLogger)
+			if requestID != "" {
+				ctx = context.WithValue(ctx, requestIDKey{}, requestID)
+			}
+			r = r.WithContext(ctx)
+
+			if skipPath(config.SkipPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rw := &responseWriter{ResponseWriter: w}
+			defer func() {
+				if p := recover(); p != nil {
+					// Log the request as failed, then let the server handle the panic
+					if !rw.wroteHeader {
+						rw.status = http.StatusInternalServerError
+					}
+					logAccess(reqLogger, config, r, rw, time.Since(start))
+					panic(p)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+			logAccess(reqLogger, config, r, rw, time.Since(start))
+		})
+	}
+}
+
+// logAccess writes the access log entry of a finished request
+func logAccess(l Logger, config MiddlewareConfig, r *http.Request, rw *responseWriter, latency time.Duration) {
+	status := rw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	fields := []interface{}{
+		"status", status,
+		"bytes", rw.bytes,
+		"latency", latency,
+		"client_ip", clientIP(r, config.TrustProxy),
+	}
+
+	slow := config.SlowThreshold > 0 && latency > config.SlowThreshold
+	switch {
+	case status >= http.StatusInternalServerError:
+		l.Error(config.Message, fields...)
+	case slow:
+		l.Warn(config.Message, append(fields, "slow", true)...)
+	default:
+		l.Info(config.Message, fields...)
+	}
+}
+
+// responseWriter records the status and size of a response
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher when the underlying writer does
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying writer does, e.g. for websockets
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	if !w.wroteHeader {
+		w.status = http.StatusSwitchingProtocols
+		w.wroteHeader = true
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// skipPath reports whether path matches one of the skip paths
+func skipPath(skipPaths []string, path string) bool {
+	for _, skip := range skipPaths {
+		if prefix, ok := strings.CutSuffix(skip, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == skip {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the IP of the client, taking proxy headers into account
+// only when they are trusted
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// validRequestID returns id if it is safe to log and echo back, or ""
+func validRequestID(id string) string {
+	if id == "" || len(id) > maxRequestIDLength {
+		return ""
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return ""
+		}
+	}
+	return id
+}
+
+// newRequestID returns a random 128-bit hex ID
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// output owns the destination of a logger: the configured writer, the
+// optional async buffer in front of it and the file opened for it.
+type output struct {
+	writer io.Writer
+	async  *AsyncWriter
+	file   *os.File
+	// color is set when the destination is a terminal
+	color bool
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// newOutput opens the destination described by the configuration
+func newOutput(config Config) (*output, error) {
+	writer, err := getWriter(config)
+	if err != nil {
+		return nil, err
+	}
+	out := &output{writer: writer}
+	out.color = useColor(out.writer)
+
+	if file, ok := out.writer.(*os.File); ok && file != os.Stdout && file != os.Stderr {
+		out.file = file
+	}
+
+	if config.Async {
+		out.async = NewAsyncWriter(out.writer, config.BufferSize, DropPolicy(config.DropPolicy))
+		out.writer = out.async
+	}
+
+	return out, nil
+}
+
+// Write implements io.Writer
+func (o *output) Write(p []byte) (int, error) {
+	return o.writer.Write(p)
+}
+
+// Sync flushes buffered entries and syncs the destination
+func (o *output) Sync() error {
+	if o.async != nil {
+		return o.async.Sync()
+	}
+	return syncWriter(o.writer)
+}
+
+// Close flushes buffered entries and closes the file opened for the logger.
+// Standard output and standard error are never closed.
+func (o *output) Close() error {
+	o.closeOnce.Do(func() {
+		if o.async != nil {
+			o.closeErr = o.async.Close()
+		} else {
+			o.closeErr = syncWriter(o.writer)
+		}
+
+		if o.file != nil {
+			if err := o.file.Close(); err != nil && o.closeErr == nil {
+				o.closeErr = err
+			}
+		}
+	})
+	return o.closeErr
+}
+
+// dropped returns the number of entries discarded by the async buffer
+func (o *output) dropped() uint64 {
+	if o.async == nil {
+		return 0
+	}
+	return o.async.Dropped()
+}
+
+// syncWriter syncs w when it supports it. Standard output and standard error
+// are skipped since fsync fails on terminals and pipes.
+func syncWriter(w io.Writer) error {
+	if file, ok := w.(*os.File); ok && (file == os.Stdout || file == os.Stderr) {
+		return nil
+	}
+	if syncer, ok := w.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package logger
+
+import "time"
+
+// backend is implemented by every logger implementation. The shared pipeline
+// decides whether and what to log, and the backend does the actual writing.
+type backend interface {
+	enabled(level LogLevel) bool
+	write(level LogLevel, msg string, fields []interface{}, site callSite)
+	// context returns the fields added with WithField and WithFields
+	context() map[string]interface{}
+}
+
+// pipeline holds the processing shared by all backends. A single pipeline is
+// shared by a logger and every logger derived from it via WithField and WithFields.
+type pipeline struct {
+	levels     *levelResolver
+	redactor   *Redactor
+	sampler    *sampler
+	out        *output
+	sinks      []Sink
+	caller     bool
+	stackTrace bool
+
+	// exitFunc replaces os.Exit when set
+	exitFunc        func(code int)
+	shutdownTimeout time.Duration
+}
+
+// newPipeline builds the processing pipeline described by the configuration.
+// The configuration is expected to be validated.
+func newPipeline(config Config) (*pipeline, error) {
+	p := &pipeline{
+		levels:     newLevelResolver(config),
+		redactor:   newRedactor(config),
+		sampler:    newSampler(config),
+		caller:     !config.DisableCaller,
+		stackTrace: config.StackTrace,
+
+		exitFunc:        config.ExitFunc,
+		shutdownTimeout: config.ShutdownTimeout,
+	}
+	if p.shutdownTimeout <= 0 {
+		p.shutdownTimeout = defaultShutdownTimeout
+	}
+
+	sink, err := newOutputSink(config)
+	if err != nil {
+		return nil, err
+	}
+	if sink != nil {
+		p.sinks = append(p.sinks, sink)
+	}
+
+	shipper, err := newShipperSink(config)
+	if err != nil {
+		p.closeSinks()
+		return nil, err
+	}
+	if shipper != nil {
+		p.sinks = append(p.sinks, shipper)
+	}
+
+	p.out, err = newOutput(config)
+	if err != nil {
+		p.closeSinks()
+		return nil, err
+	}
+
+	for _, hook := range config.Hooks {
+		if hook != nil {
+			p.sinks = append(p.sinks, newHookSink(hook, config))
+		}
+	}
+
+	if p.sampler != nil {
+		p.sampler.emit = p.emit
+	}
+	return p, nil
+}
+
+// closeSinks releases the sinks opened by a pipeline that failed to build
+func (p *pipeline) closeSinks() {
+	for _, sink := range p.sinks {
+		_ = sink.Close()
+	}
+}
+
+// log runs an entry through redaction and sampling before handing it to the
+// backend. It must be called directly from the Logger method used by the
+// caller so the reported call site is correct.
+func (p *pipeline) log(b backend, level LogLevel, msg string, fields []interface{}) {
+	if !b.enabled(level) {
+		return
+	}
+
+	msg = p.redactor.Message(msg)
+	fields = p.pairs(fields)
+
+	var site callSite
+	if p.caller {
+		// Skip pipeline.log and the Logger method
+		site.frame = callerFrame(2)
+	}
+	if p.stackTrace && (level == ErrorLevel || level == FatalLevel) {
+		site.stack = stackTrace(2)
+	}
+
+	// Fatal entries are never sampled so the process always exits
+	if level != FatalLevel && !p.sampler.allow(b, level, msg, site) {
+		return
+	}
+
+	p.emit(b, level, msg, fields, site)
+}
+
+// emit delivers a processed entry to the sinks and the backend. Sinks go
+// first since the backend exits the process on Fatal.
+func (p *pipeline) emit(b backend, level LogLevel, msg string, fields []interface{}, site callSite) {
+	if len(p.sinks) > 0 {
+		entry := newEntry(b.context(), level, msg, fields, site)
+		for _, sink := range p.sinks {
+			if err := sink.WriteEntry(entry); err != nil {
+				// A failing sink must not break logging to the other outputs
+				_ = err
+			}
+		}
+	}
+
+	b.write(level, msg, fields, site)
+}
+
+// field prepares a single field value: errors are expanded into ErrorInfo
+// and sensitive data is redacted
+func (p *pipeline) field(key string, value interface{}) interface{} {
+	if err, ok := value.(error); ok && err != nil {
+		value = NewErrorInfo(err)
+	}
+	return p.redactor.Field(key, value)
+}
+
+// fields prepares a copy of a field map
+func (p *pipeline) fields(fields map[string]interface{}) map[string]interface{} {
+	prepared := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		prepared[key] = p.field(key, value)
+	}
+	return prepared
+}
+
+// pairs prepares a copy of alternating key-value pairs. Non-string keys are
+// passed through unchanged.
+func (p *pipeline) pairs(fields []interface{}) []interface{} {
+	if len(fields) == 0 {
+		return fields
+	}
+	prepared := make([]interface{}, len(fields))
+	copy(prepared, fields)
+	for i := 0; i < len(prepared)-1; i += 2 {
+		if key, ok := prepared[i].(string); ok {
+			prepared[i+1] = p.field(key, prepared[i+1])
+		}
+	}
+	return prepared
+}
+
+// sync writes pending dedup summaries and flushes the output and sinks
+func (p *pipeline) sync() error {
+	p.sampler.flushAll()
+
+	err := p.out.Sync()
+	for _, sink := range p.sinks {
+		if sinkErr := sink.Sync(); sinkErr != nil && err == nil {
+			err = sinkErr
+		}
+	}
+	return err
+}
+
+// close writes pending dedup summaries and closes the output and sinks
+func (p *pipeline) close() error {
+	p.sampler.close()
+
+	err := p.out.Close()
+	for _, sink := range p.sinks {
+		if sinkErr := sink.Close(); sinkErr != nil && err == nil {
+			err = sinkErr
+		}
+	}
+	return err
+}
+
+// dropped returns the number of entries discarded by the async buffer and
+// by sinks that count their drops
+func (p *pipeline) dropped() uint64 {
+	n := p.out.dropped()
+	for _, sink := range p.sinks {
+		if d, ok := sink.(interface{ Dropped() uint64 }); ok {
+			n += d.Dropped()
+		}
+	}
+	return n
+}
@@ -0,0 +1,256 @@
+package logger
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// RedactedValue replaces sensitive values in log output
+const RedactedValue = "[REDACTED]"
+
+// maxRedactDepth limits how deep nested maps, slices and structs are inspected
+const maxRedactDepth = 8
+
+// DefaultRedactKeys are the field keys redacted when Config.RedactKeys is empty
+var DefaultRedactKeys = []string{"password", "secret_key", "token", "authorization"}
+
+// DefaultRedactPatterns match secrets embedded in messages and string values
+var DefaultRedactPatterns = []*regexp.Regexp{
+	// Bearer tokens, e.g. "Authorization: Bearer abc.def"
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`),
+	// JSON Web Tokens (header.payload.signature)
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+}
+
+// Redactor masks sensitive data in log messages and fields.
+//
+// A field is redacted when its normalized key (lower case, "-" replaced by "_")
+// equals a denylisted key or ends with "_" followed by one, so "token" also
+// covers "access_token". String values and messages are scanned with the
+// configured patterns. Structs are walked and fields tagged `log:"redact"`
+// are masked as well.
+type Redactor struct {
+	keys     map[string]struct{}
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor creates a redactor with the given key denylist and patterns
+func NewRedactor(keys []string, patterns []*regexp.Regexp) *Redactor {
+	r := &Redactor{
+		keys:     make(map[string]struct{}, len(keys)),
+		patterns: patterns,
+	}
+	for _, key := range keys {
+		if key = normalizeKey(key); key != "" {
+			r.keys[key] = struct{}{}
+		}
+	}
+	return r
+}
+
+// newRedactor builds the redactor described by the logger configuration
+func newRedactor(config Config) *Redactor {
+	if config.DisableRedaction {
+		return nil
+	}
+
+	keys := config.RedactKeys
+	if len(keys) == 0 {
+		keys = DefaultRedactKeys
+	}
+
+	patterns := append([]*regexp.Regexp{}, DefaultRedactPatterns...)
+	for _, expr := range config.RedactPatterns {
+		if expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			// Invalid patterns are rejected by Config.Validate
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+
+	return NewRedactor(keys, patterns)
+}
+
+// Message masks pattern matches inside a log message
+func (r *Redactor) Message(msg string) string {
+	if r == nil {
+		return msg
+	}
+	return r.redactString(msg)
+}
+
+// Field returns the value to log for key, masking it when sensitive
+func (r *Redactor) Field(key string, value interface{}) interface{} {
+	if r == nil {
+		return value
+	}
+	if r.isSensitive(key) {
+		return RedactedValue
+	}
+	return r.Value(value)
+}
+
+// Value masks secrets inside an arbitrary value. Strings are scanned with the
+// configured patterns, and maps, slices and structs are inspected
+// recursively. Values without anything to redact are returned unchanged.
+func (r *Redactor) Value(value interface{}) interface{} {
+	if r == nil || value == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case string:
+		return r.redactString(v)
+	case ErrorInfo:
+		return v.redact(r)
+	}
+	if redacted, changed := r.redactValue(reflect.ValueOf(value), 0); changed {
+		return redacted
+	}
+	return value
+}
+
+func (r *Redactor) isSensitive(key string) bool {
+	key = normalizeKey(key)
+	if _, ok := r.keys[key]; ok {
+		return true
+	}
+	for denied := range r.keys {
+		if strings.HasSuffix(key, "_"+denied) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) redactString(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, RedactedValue)
+	}
+	return s
+}
+
+// redactValue walks v and reports whether anything was masked. Only changed
+// values are rebuilt, as map[string]interface{} for maps and structs and
+// []interface{} for slices and arrays.
+func (r *Redactor) redactValue(v reflect.Value, depth int) (interface{}, bool) {
+	if depth > maxRedactDepth || !v.IsValid() {
+		return nil, false
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, false
+		}
+		return r.redactValue(v.Elem(), depth+1)
+
+	case reflect.String:
+		original := v.String()
+		if redacted := r.redactString(original); redacted != original {
+			return redacted, true
+		}
+		return nil, false
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		result := make(map[string]interface{}, v.Len())
+		changed := false
+		iter := v.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			if r.isSensitive(key) {
+				result[key] = RedactedValue
+				changed = true
+				continue
+			}
+			if redacted, ok := r.redactValue(iter.Value(), depth+1); ok {
+				result[key] = redacted
+				changed = true
+				continue
+			}
+			result[key] = iter.Value().Interface()
+		}
+		return result, changed
+
+	case reflect.Slice, reflect.Array:
+		// Byte slices are raw data rather than a list of values
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return nil, false
+		}
+		var result []interface{}
+		for i := 0; i < v.Len(); i++ {
+			redacted, ok := r.redactValue(v.Index(i), depth+1)
+			if !ok {
+				if result != nil {
+					result[i] = v.Index(i).Interface()
+				}
+				continue
+			}
+			if result == nil {
+				// First change: copy the elements walked so far
+				result = make([]interface{}, v.Len())
+				for j := 0; j < i; j++ {
+					result[j] = v.Index(j).Interface()
+				}
+			}
+			result[i] = redacted
+		}
+		return result, result != nil
+
+	case reflect.Struct:
+		return r.redactStruct(v, depth)
+
+	default:
+		return nil, false
+	}
+}
+
+func (r *Redactor) redactStruct(v reflect.Value, depth int) (interface{}, bool) {
+	t := v.Type()
+	result := make(map[string]interface{}, t.NumField())
+	changed := false
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		if field.Tag.Get("log") == "redact" || r.isSensitive(name) {
+			result[name] = RedactedValue
+			changed = true
+			continue
+		}
+		if redacted, ok := r.redactValue(v.Field(i), depth+1); ok {
+			result[name] = redacted
+			changed = true
+			continue
+		}
+		result[name] = v.Field(i).Interface()
+	}
+
+	return result, changed
+}
+
+// normalizeKey lower-cases a field key and maps "-" to "_"
+func normalizeKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "-", "_")
+}
@@ -0,0 +1,222 @@
+package logger
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestRedactorField(t *testing.T) {
+	r := NewRedactor(DefaultRedactKeys, DefaultRedactPatterns)
+
+	tests := []struct {
+		name  string
+		key   string
+		value interface{}
+		want  interface{}
+	}{
+		{"exact key", "password", "hunter2", RedactedValue},
+		{"case insensitive", "Password", "hunter2", RedactedValue},
+		{"dash normalized", "Secret-Key", "abc", RedactedValue},
+		{"suffix", "access_token", "abc", RedactedValue},
+		{"suffix with dash", "refresh-token", "abc", RedactedValue},
+		{"not a suffix match", "tokens_left", 3, 3},
+		{"plain value", "user", "jane", "jane"},
+		{"bearer token in value", "header", "Bearer abc.def.ghi", RedactedValue},
+		{"jwt in value", "note", "got eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig", "got " + RedactedValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Field(tt.key, tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Field(%q, %v) = %v, want %v", tt.key, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorCustomKeysAndPatterns(t *testing.T) {
+	r := NewRedactor([]string{"ssn"}, []*regexp.Regexp{regexp.MustCompile(`\d{4}-\d{4}`)})
+
+	if got := r.Field("user_ssn", "123"); got != RedactedValue {
+		t.Errorf("Field(user_ssn) = %v, want %v", got, RedactedValue)
+	}
+	if got := r.Field("password", "hunter2"); got != "hunter2" {
+		t.Errorf("Field(password) = %v, want it unchanged with a custom denylist", got)
+	}
+	if got := r.Message("card 1234-5678 declined"); got != "card "+RedactedValue+" declined" {
+		t.Errorf("Message() = %q", got)
+	}
+}
+
+func TestRedactorMessage(t *testing.T) {
+	r := NewRedactor(DefaultRedactKeys, DefaultRedactPatterns)
+
+	got := r.Message("calling api with Authorization: Bearer abc.def-ghi_jkl")
+	want := "calling api with Authorization: " + RedactedValue
+	if got != want {
+		t.Errorf("Message() = %q, want %q", got, want)
+	}
+
+	if got := r.Message("nothing to hide"); got != "nothing to hide" {
+		t.Errorf("Message() = %q, want it unchanged", got)
+	}
+
+	var nilRedactor *Redactor
+	if got := nilRedactor.Message("Bearer abc"); got != "Bearer abc" {
+		t.Errorf("nil Redactor Message() = %q, want it unchanged", got)
+	}
+}
+
+type loginRequest struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+	PIN      string `log:"redact"`
+	Note     string `json:"note"`
+	Skipped  string `json:"-"`
+	internal string
+}
+
+func TestRedactorStruct(t *testing.T) {
+	r := NewRedactor(DefaultRedactKeys, DefaultRedactPatterns)
+
+	got := r.Value(loginRequest{User: "jane", Password: "hunter2", PIN: "1234", Note: "Bearer abc", Skipped: "x", internal: "y"})
+	want := map[string]interface{}{
+		"user":     "jane",
+		"password": RedactedValue,
+		"PIN":      RedactedValue,
+		"note":     RedactedValue,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Value() = %#v, want %#v", got, want)
+	}
+
+	// Pointers are followed
+	if got := r.Value(&loginRequest{PIN: "1234"}); !reflect.DeepEqual(got.(map[string]interface{})["PIN"], RedactedValue) {
+		t.Errorf("Value(pointer) = %#v, want PIN redacted", got)
+	}
+}
+
+func TestRedactorUnchangedValues(t *testing.T) {
+	r := NewRedactor(DefaultRedactKeys, DefaultRedactPatterns)
+
+	type plain struct{ Name string }
+	values := []interface{}{
+		plain{Name: "jane"},
+		[]string{"a", "b"},
+		map[string]int{"a": 1},
+		[]byte("Bearer abc"),
+		42,
+	}
+	for _, value := range values {
+		if got := r.Value(value); !reflect.DeepEqual(got, value) {
+			t.Errorf("Value(%#v) = %#v, want it unchanged", value, got)
+		}
+	}
+}
+
+func TestRedactorNested(t *testing.T) {
+	r := NewRedactor(DefaultRedactKeys, DefaultRedactPatterns)
+
+	type user struct {
+		Name string
+		Pass string `log:"redact"`
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{
+			name:  "slice of structs",
+			value: []user{{Name: "a", Pass: "s3cret"}, {Name: "b"}},
+			want: []interface{}{
+				map[string]interface{}{"Name": "a", "Pass": RedactedValue},
+				map[string]interface{}{"Name": "b", "Pass": RedactedValue},
+			},
+		},
+		{
+			name:  "slice of strings",
+			value: []string{"ok", "Bearer abc.def.ghi"},
+			want:  []interface{}{"ok", RedactedValue},
+		},
+		{
+			name:  "array",
+			value: [2]string{"Bearer abc", "ok"},
+			want:  []interface{}{RedactedValue, "ok"},
+		},
+		{
+			name:  "map with sensitive key",
+			value: map[string]interface{}{"user": "jane", "token": "abc"},
+			want:  map[string]interface{}{"user": "jane", "token": RedactedValue},
+		},
+		{
+			name: "map of slices of maps",
+			value: map[string]interface{}{
+				"items": []map[string]string{{"id": "1"}, {"id": "2", "api_token": "abc"}},
+			},
+			want: map[string]interface{}{
+				"items": []interface{}{
+					map[string]string{"id": "1"},
+					map[string]interface{}{"id": "2", "api_token": RedactedValue},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Value(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Value() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorMaxDepth(t *testing.T) {
+	r := NewRedactor(DefaultRedactKeys, DefaultRedactPatterns)
+
+	var value interface{} = "Bearer abc"
+	for i := 0; i < maxRedactDepth+2; i++ {
+		value = []interface{}{value}
+	}
+	// Too deep to be inspected, so it is returned as is
+	if got := r.Value(value); !reflect.DeepEqual(got, value) {
+		t.Errorf("Value() = %#v, want it unchanged past maxRedactDepth", got)
+	}
+}
+
+func TestLoggerRedaction(t *testing.T) {
+	for _, backend := range []string{"simple", "logrus", "zap"} {
+		t.Run(backend, func(t *testing.T) {
+			l, entries := newTestLogger(t, Config{Backend: backend})
+
+			l.WithField("api_token", "abc").Info("login with Bearer abc.def", "password", "hunter2", "user", "jane")
+
+			got := entries()
+			if len(got) != 1 {
+				t.Fatalf("got %d entries, want 1", len(got))
+			}
+			entry := got[0]
+			if entry["msg"] != "login with "+RedactedValue {
+				t.Errorf("msg = %v", entry["msg"])
+			}
+			for key, want := range map[string]interface{}{"password": RedactedValue, "api_token": RedactedValue, "user": "jane"} {
+				if entry[key] != want {
+					t.Errorf("%s = %v, want %v", key, entry[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoggerDisableRedaction(t *testing.T) {
+	l, entries := newTestLogger(t, Config{DisableRedaction: true})
+
+	l.Info("login", "password", "hunter2")
+
+	if got := entries(); len(got) != 1 || got[0]["password"] != "hunter2" {
+		t.Errorf("entries = %v, want the password logged as is", got)
+	}
+}
@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultSampleInterval is used when sampling is enabled without an interval
+const defaultSampleInterval = time.Second
+
+// RepeatedField is the field holding the number of collapsed duplicates
+const RepeatedField = "repeated"
+
+// sampleKey identifies entries that are sampled together
+type sampleKey struct {
+	level LogLevel
+	// name is the name of the logger, so Named loggers are sampled apart
+	name string
+	msg  string
+}
+
+// sampleCounter tracks entries with the same key during one interval
+type sampleCounter struct {
+	resetAt time.Time
+	count   int
+
+	// Dedup state: the backend and call site of the first entry, used to
+	// write the summary once the interval ends
+	backend    backend
+	site       callSite
+	suppressed int
+	timer      *time.Timer
+}
+
+// sampler limits how often entries with the same level, logger name and
+// message are written.
+//
+// In sampling mode the first initial entries per interval are logged, then
+// every thereafter-th one. In dedup mode the first entry is logged and the
+// repeats seen during the interval are collapsed into a single summary entry
+// carrying a RepeatedField count and the context fields, but none of the
+// per-call fields.
+type sampler struct {
+	initial    int
+	thereafter int
+	interval   time.Duration
+	dedup      bool
+
+	// emit writes dedup summaries through the pipeline
+	emit func(b backend, level LogLevel, msg string, fields []interface{}, site callSite)
+
+	mu        sync.Mutex
+	counters  map[sampleKey]*sampleCounter
+	nextSweep time.Time
+	// closed stops summaries once the output is closed, and flushing tracks
+	// the ones being written
+	closed   bool
+	flushing sync.WaitGroup
+}
+
+// newSampler returns nil when neither sampling nor dedup is configured
+func newSampler(config Config) *sampler {
+	if config.SampleInitial <= 0 && !config.Dedup {
+		return nil
+	}
+
+	interval := config.SampleInterval
+	if interval <= 0 {
+		interval = defaultSampleInterval
+	}
+
+	return &sampler{
+		initial:    config.SampleInitial,
+		thereafter: config.SampleThereafter,
+		interval:   interval,
+		dedup:      config.Dedup,
+		counters:   make(map[sampleKey]*sampleCounter),
+	}
+}
+
+// allow reports whether the entry should be written now
+func (s *sampler) allow(b backend, level LogLevel, msg string, site callSite) bool {
+	if s == nil {
+		return true
+	}
+
+	now := time.Now()
+	name, _ := b.context()[NameField].(string)
+	key := sampleKey{level: level, name: name, msg: msg}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	counter, ok := s.counters[key]
+	if !ok || now.After(counter.resetAt) {
+		counter = &sampleCounter{resetAt: now.Add(s.interval)}
+		s.counters[key] = counter
+	}
+	counter.count++
+
+	if s.dedup {
+		if counter.count == 1 {
+			counter.backend = b
+			counter.site = site
+			if !s.closed {
+				counter.timer = time.AfterFunc(s.interval, func() { s.flush(key, counter) })
+			}
+			return true
+		}
+		counter.suppressed++
+		return false
+	}
+
+	if counter.count <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (counter.count-s.initial)%s.thereafter == 0
+}
+
+// flush writes the dedup summary for a finished interval
+func (s *sampler) flush(key sampleKey, counter *sampleCounter) {
+	s.mu.Lock()
+	if s.counters[key] == counter {
+		delete(s.counters, key)
+	}
+	if counter.timer != nil {
+		counter.timer.Stop()
+	}
+	suppressed := counter.suppressed
+	counter.suppressed = 0
+	if s.closed || suppressed == 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.flushing.Add(1)
+	s.mu.Unlock()
+	defer s.flushing.Done()
+
+	// The repeats are grouped by level and message only, so the per-call
+	// fields of any one of them would be misleading. The backend still adds
+	// the context fields.
+	s.emit(counter.backend, key.level, key.msg, []interface{}{RepeatedField, suppressed}, counter.site)
+}
+
+// flushAll writes the dedup summaries of all pending intervals immediately
+func (s *sampler) flushAll() {
+	if s == nil || !s.dedup {
+		return
+	}
+
+	s.mu.Lock()
+	pending := make(map[sampleKey]*sampleCounter, len(s.counters))
+	for key, counter := range s.counters {
+		pending[key] = counter
+	}
+	s.mu.Unlock()
+
+	for key, counter := range pending {
+		s.flush(key, counter)
+	}
+}
+
+// close writes the pending dedup summaries and stops the flush timers, so no
+// summary is written after the output is closed
+func (s *sampler) close() {
+	if s == nil {
+		return
+	}
+	s.flushAll()
+
+	s.mu.Lock()
+	s.closed = true
+	for _, counter := range s.counters {
+		if counter.timer != nil {
+			counter.timer.Stop()
+		}
+	}
+	s.mu.Unlock()
+
+	// Wait for summaries whose timer fired before closed was set
+	s.flushing.Wait()
+}
+
+// sweep drops expired sampling counters so unique messages don't accumulate.
+// Dedup counters are removed by their flush timer instead.
+func (s *sampler) sweep(now time.Time) {
+	if s.dedup || now.Before(s.nextSweep) {
+		return
+	}
+	for key, counter := range s.counters {
+		if now.After(counter.resetAt) {
+			delete(s.counters, key)
+		}
+	}
+	s.nextSweep = now.Add(s.interval)
+}
@@ -0,0 +1,242 @@
+package logger
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// testBackend is a backend with fixed context fields for sampler tests
+type testBackend struct {
+	fields map[string]interface{}
+}
+
+func (b testBackend) enabled(LogLevel) bool                           { return true }
+func (b testBackend) write(LogLevel, string, []interface{}, callSite) {}
+func (b testBackend) context() map[string]interface{}                 { return b.fields }
+
+// summary is a dedup summary written by a sampler
+type summary struct {
+	level  LogLevel
+	msg    string
+	fields []interface{}
+}
+
+// newTestSampler returns a sampler recording the summaries it emits
+func newTestSampler(config Config) (*sampler, func() []summary) {
+	s := newSampler(config)
+
+	var mu sync.Mutex
+	var summaries []summary
+	s.emit = func(b backend, level LogLevel, msg string, fields []interface{}, site callSite) {
+		mu.Lock()
+		defer mu.Unlock()
+		summaries = append(summaries, summary{level: level, msg: msg, fields: fields})
+	}
+	return s, func() []summary {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]summary(nil), summaries...)
+	}
+}
+
+func TestSamplerDisabled(t *testing.T) {
+	if s := newSampler(Config{}); s != nil {
+		t.Fatalf("newSampler() = %v, want nil without sampling or dedup", s)
+	}
+
+	var s *sampler
+	if !s.allow(testBackend{}, InfoLevel, "msg", callSite{}) {
+		t.Error("nil sampler must allow every entry")
+	}
+	s.flushAll()
+	s.close()
+}
+
+func TestSamplerInitialThereafter(t *testing.T) {
+	s := newSampler(Config{SampleInitial: 3, SampleThereafter: 5, SampleInterval: time.Hour})
+
+	var allowed []int
+	for i := 1; i <= 20; i++ {
+		if s.allow(testBackend{}, InfoLevel, "msg", callSite{}) {
+			allowed = append(allowed, i)
+		}
+	}
+
+	// The first 3, then every 5th after them
+	want := []int{1, 2, 3, 8, 13, 18}
+	if len(allowed) != len(want) {
+		t.Fatalf("allowed entries %v, want %v", allowed, want)
+	}
+	for i := range want {
+		if allowed[i] != want[i] {
+			t.Fatalf("allowed entries %v, want %v", allowed, want)
+		}
+	}
+}
+
+func TestSamplerInitialOnly(t *testing.T) {
+	s := newSampler(Config{SampleInitial: 2, SampleInterval: time.Hour})
+
+	count := 0
+	for i := 0; i < 10; i++ {
+		if s.allow(testBackend{}, InfoLevel, "msg", callSite{}) {
+			count++
+		}
+	}
+	if count != 2 {
+		t.Errorf("allowed %d entries, want 2 without SampleThereafter", count)
+	}
+}
+
+func TestSamplerKeys(t *testing.T) {
+	s := newSampler(Config{SampleInitial: 1, SampleInterval: time.Hour})
+
+	payments := testBackend{fields: map[string]interface{}{NameField: "payments"}}
+	orders := testBackend{fields: map[string]interface{}{NameField: "orders"}}
+
+	cases := []struct {
+		b     backend
+		level LogLevel
+		msg   string
+	}{
+		{testBackend{}, InfoLevel, "msg"},
+		{testBackend{}, WarnLevel, "msg"},
+		{testBackend{}, InfoLevel, "other"},
+		{payments, InfoLevel, "msg"},
+		{orders, InfoLevel, "msg"},
+	}
+	for _, c := range cases {
+		if !s.allow(c.b, c.level, c.msg, callSite{}) {
+			t.Errorf("first %s %q from %v was sampled out", c.level, c.msg, c.b.context())
+		}
+	}
+	if s.allow(payments, InfoLevel, "msg", callSite{}) {
+		t.Error("second entry of the payments logger was not sampled out")
+	}
+}
+
+func TestSamplerIntervalReset(t *testing.T) {
+	s := newSampler(Config{SampleInitial: 1, SampleInterval: 20 * time.Millisecond})
+
+	if !s.allow(testBackend{}, InfoLevel, "msg", callSite{}) {
+		t.Fatal("first entry was sampled out")
+	}
+	if s.allow(testBackend{}, InfoLevel, "msg", callSite{}) {
+		t.Fatal("second entry within the interval was not sampled out")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if !s.allow(testBackend{}, InfoLevel, "msg", callSite{}) {
+		t.Error("first entry of the next interval was sampled out")
+	}
+}
+
+func TestSamplerDedupSummary(t *testing.T) {
+	s, summaries := newTestSampler(Config{Dedup: true, SampleInterval: 20 * time.Millisecond})
+
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		if s.allow(testBackend{}, ErrorLevel, "boom", callSite{}) {
+			allowed++
+		}
+	}
+	if allowed != 1 {
+		t.Fatalf("allowed %d entries, want only the first", allowed)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	got := summaries()
+	if len(got) != 1 {
+		t.Fatalf("got %d summaries, want 1", len(got))
+	}
+	if got[0].level != ErrorLevel || got[0].msg != "boom" {
+		t.Errorf("summary = %s %q, want error \"boom\"", got[0].level, got[0].msg)
+	}
+	if len(got[0].fields) != 2 || got[0].fields[0] != RepeatedField || got[0].fields[1] != 19 {
+		t.Errorf("summary fields = %v, want only [%s 19]", got[0].fields, RepeatedField)
+	}
+}
+
+func TestSamplerDedupNoRepeats(t *testing.T) {
+	s, summaries := newTestSampler(Config{Dedup: true, SampleInterval: 10 * time.Millisecond})
+
+	s.allow(testBackend{}, ErrorLevel, "once", callSite{})
+	time.Sleep(30 * time.Millisecond)
+
+	if got := summaries(); len(got) != 0 {
+		t.Errorf("got summaries %v for an entry logged once", got)
+	}
+}
+
+func TestSamplerFlushAll(t *testing.T) {
+	s, summaries := newTestSampler(Config{Dedup: true, SampleInterval: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		s.allow(testBackend{}, ErrorLevel, "boom", callSite{})
+	}
+	s.flushAll()
+
+	if got := summaries(); len(got) != 1 || got[0].fields[1] != 2 {
+		t.Fatalf("summaries after flushAll = %v, want one with 2 repeats", got)
+	}
+
+	// The interval starts over, so the next entry is logged again
+	if !s.allow(testBackend{}, ErrorLevel, "boom", callSite{}) {
+		t.Error("entry after flushAll was suppressed")
+	}
+}
+
+func TestSamplerCloseStopsTimers(t *testing.T) {
+	s, summaries := newTestSampler(Config{Dedup: true, SampleInterval: 20 * time.Millisecond})
+
+	for i := 0; i < 3; i++ {
+		s.allow(testBackend{}, ErrorLevel, "boom", callSite{})
+	}
+	s.close()
+	if got := summaries(); len(got) != 1 {
+		t.Fatalf("got %d summaries on close, want the pending one", len(got))
+	}
+
+	// Entries logged after close must not produce summaries later on
+	for i := 0; i < 3; i++ {
+		s.allow(testBackend{}, ErrorLevel, "late", callSite{})
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	if got := summaries(); len(got) != 1 {
+		t.Errorf("got %d summaries after close, want none written after it", len(got)-1)
+	}
+}
+
+func TestLoggerDedup(t *testing.T) {
+	for _, backend := range []string{"simple", "logrus", "zap"} {
+		t.Run(backend, func(t *testing.T) {
+			l, entries := newTestLogger(t, Config{Backend: backend, Dedup: true, SampleInterval: time.Hour})
+
+			db := l.WithField("host", "db")
+			for i := 0; i < 20; i++ {
+				db.Error("boom", "i", i)
+			}
+			l.Named("payments").Error("boom")
+
+			got := entries()
+			if len(got) != 3 {
+				t.Fatalf("got %d entries, want the first, the payments one and the summary: %v", len(got), got)
+			}
+			first, named, sum := got[0], got[1], got[2]
+			if first["i"] != float64(0) {
+				t.Errorf("first entry = %v", first)
+			}
+			if named[NameField] != "payments" {
+				t.Errorf("named logger entry = %v, want it logged separately", named)
+			}
+			if sum["repeated"] != float64(19) || sum["host"] != "db" {
+				t.Errorf("summary = %v, want repeated=19 and host=db", sum)
+			}
+			if _, ok := sum["i"]; ok {
+				t.Errorf("summary = %v, must not carry per-call fields", sum)
+			}
+		})
+	}
+}
@@ -0,0 +1,530 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShipperKind represents the API a ShipperSink pushes to
+type ShipperKind string
+
+const (
+	LokiShipper          ShipperKind = "loki"
+	ElasticsearchShipper ShipperKind = "elasticsearch"
+)
+
+// Shipper defaults, used when the corresponding ShipperConfig field is zero
+const (
+	defaultShipBatchSize     = 500
+	defaultShipFlushInterval = time.Second
+	defaultShipMaxRetries    = 5
+	defaultShipRetryBackoff  = 500 * time.Millisecond
+	defaultShipTimeout       = 10 * time.Second
+	defaultShipIndex         = "logs"
+
+	// maxShipRetryBackoff caps the exponential backoff between retries
+	maxShipRetryBackoff = 30 * time.Second
+	// maxSpillFiles bounds the spill directory; further batches are dropped
+	maxSpillFiles = 1000
+	// spillExt is the extension of spilled batch files
+	spillExt = ".spill"
+)
+
+// ErrShipperQueueFull is returned by ShipperSink.WriteEntry when the entry
+// queue is full because the endpoint can't keep up
+var ErrShipperQueueFull = errors.New("log shipper queue is full")
+
+// ShipperConfig holds the settings of a ShipperSink
+type ShipperConfig struct {
+	// Kind selects the Loki push API or the Elasticsearch bulk API
+	Kind ShipperKind
+	// URL is the full endpoint, e.g. http://loki:3100/loki/api/v1/push or
+	// http://elasticsearch:9200/_bulk
+	URL string
+	// Index is the Elasticsearch index, "logs" by default
+	Index string
+	// Labels are static Loki stream labels, e.g. {"app": "api"}
+	Labels map[string]string
+	// LabelFields lists the entry fields promoted to Loki stream labels. The
+	// level is always a label.
+	LabelFields []string
+	// Headers are added to every request, e.g. Authorization or X-Scope-OrgID
+	Headers map[string]string
+
+	// BatchSize is the number of entries sent per request, 500 by default
+	BatchSize int
+	// FlushInterval is how often a partial batch is sent, 1s by default
+	FlushInterval time.Duration
+	// MaxRetries is how many times a failed request is retried, 5 by default
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry. It doubles on each
+	// attempt up to 30s. The default is 500ms.
+	RetryBackoff time.Duration
+	// Timeout bounds each request, 10s by default
+	Timeout time.Duration
+
+	// SpillDir is where batches are stored while the endpoint is down. They
+	// are resent once it is reachable again. When empty, such batches are dropped.
+	SpillDir string
+}
+
+// ShipperSink batches entries as JSON and pushes them to Loki or
+// Elasticsearch from a background goroutine.
+//
+// A batch is sent when BatchSize entries are queued or FlushInterval
+// elapses. Failed requests are retried with exponential backoff on network
+// errors, 429 and 5xx responses, and batches that still fail are spilled to
+// SpillDir. WriteEntry never blocks: when the queue is full the entry is
+// dropped and counted in Dropped.
+type ShipperSink struct {
+	config      ShipperConfig
+	client      *http.Client
+	contentType string
+
+	entries chan Entry
+	flushes chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	closed    atomic.Bool
+	closeOnce sync.Once
+	dropped   atomic.Uint64
+
+	// pending counts the spilled batches waiting to be resent, so the spill
+	// directory is only read when there is something to replay. It is only
+	// used by the background sender.
+	pending int
+}
+
+// NewShipperSink validates config, creates the spill directory and starts
+// the background sender
+func NewShipperSink(config ShipperConfig) (*ShipperSink, error) {
+	var contentType string
+	switch config.Kind {
+	case LokiShipper:
+		contentType = "application/json"
+	case ElasticsearchShipper:
+		contentType = "application/x-ndjson"
+	default:
+		return nil, fmt.Errorf("unknown log shipper kind: %s", config.Kind)
+	}
+	if config.URL == "" {
+		return nil, errors.New("log shipper URL is required")
+	}
+
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultShipBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultShipFlushInterval
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	} else if config.MaxRetries == 0 {
+		config.MaxRetries = defaultShipMaxRetries
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = defaultShipRetryBackoff
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultShipTimeout
+	}
+	if config.Index == "" {
+		config.Index = defaultShipIndex
+	}
+
+	if config.SpillDir != "" {
+		if err := os.MkdirAll(config.SpillDir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create spill directory: %w", err)
+		}
+	}
+
+	s := &ShipperSink{
+		config:      config,
+		client:      &http.Client{Timeout: config.Timeout},
+		contentType: contentType,
+		// Queue a few batches so a slow request doesn't drop entries right away
+		entries: make(chan Entry, config.BatchSize*4),
+		flushes: make(chan chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	// Batches spilled by a previous run are resent once the endpoint is reachable
+	s.pending = len(s.spilled())
+	go s.run()
+	return s, nil
+}
+
+// WriteEntry queues the entry for the next batch
+func (s *ShipperSink) WriteEntry(entry Entry) error {
+	if s.closed.Load() {
+		return ErrWriterClosed
+	}
+	select {
+	case s.entries <- entry:
+		return nil
+	default:
+		s.dropped.Add(1)
+		return ErrShipperQueueFull
+	}
+}
+
+// Sync sends the queued entries and waits for the request to finish,
+// including its retries
+func (s *ShipperSink) Sync() error {
+	if s.closed.Load() {
+		return nil
+	}
+	ack := make(chan struct{})
+	select {
+	case s.flushes <- ack:
+		<-ack
+	case <-s.done:
+	}
+	return nil
+}
+
+// Close sends the queued entries and stops the background sender. The final
+// batch is not retried; if it fails it is spilled.
+func (s *ShipperSink) Close() error {
+	s.closeOnce.Do(func() {
+		s.closed.Store(true)
+		close(s.stop)
+		<-s.done
+	})
+	return nil
+}
+
+// Dropped returns the number of entries discarded because the queue was
+// full, the endpoint rejected them or they couldn't be spilled
+func (s *ShipperSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// run collects entries into batches and sends them
+func (s *ShipperSink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, s.config.BatchSize)
+	for {
+		select {
+		case entry := <-s.entries:
+			batch = append(batch, entry)
+			if len(batch) >= s.config.BatchSize {
+				s.ship(batch, true)
+				batch = batch[:0]
+			}
+
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.ship(batch, true)
+				batch = batch[:0]
+			} else {
+				s.replay()
+			}
+
+		case ack := <-s.flushes:
+			batch = s.drain(batch, true)
+			close(ack)
+
+		case <-s.stop:
+			s.drain(batch, false)
+			return
+		}
+	}
+}
+
+// drain sends batch along with every entry still queued
+func (s *ShipperSink) drain(batch []Entry, retry bool) []Entry {
+	for {
+		select {
+		case entry := <-s.entries:
+			batch = append(batch, entry)
+			if len(batch) >= s.config.BatchSize {
+				s.ship(batch, retry)
+				batch = batch[:0]
+			}
+		default:
+			if len(batch) > 0 {
+				s.ship(batch, retry)
+			}
+			return batch[:0]
+		}
+	}
+}
+
+// ship encodes and sends a batch, spilling it if the endpoint is unreachable
+func (s *ShipperSink) ship(batch []Entry, retry bool) {
+	payload, err := s.encode(batch)
+	if err != nil {
+		s.dropped.Add(uint64(len(batch)))
+		return
+	}
+
+	retryable, err := s.send(payload, retry)
+	switch {
+	case err == nil:
+		// The endpoint is back, so resend what was spilled while it was down
+		s.replay()
+	case retryable:
+		s.spill(payload, len(batch))
+	default:
+		// The endpoint rejected the batch; resending it won't help
+		s.dropped.Add(uint64(len(batch)))
+	}
+}
+
+// send posts payload, retrying with exponential backoff when retry is set.
+// It reports whether a failure is worth retrying later.
+func (s *ShipperSink) send(payload []byte, retry bool) (bool, error) {
+	backoff := s.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := s.post(payload)
+		if err == nil || !retryable || !retry || attempt >= s.config.MaxRetries {
+			return retryable, err
+		}
+
+		// Up to 20% jitter so many instances don't retry in lockstep
+		delay := backoff + time.Duration(rand.Int63n(int64(backoff)/5+1))
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-s.stop:
+			timer.Stop()
+			return retryable, err
+		}
+
+		backoff *= 2
+		if backoff > maxShipRetryBackoff {
+			backoff = maxShipRetryBackoff
+		}
+	}
+}
+
+// post makes a single request
+func (s *ShipperSink) post(payload []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", s.contentType)
+	for key, value := range s.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to ship logs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return true, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return true, fmt.Errorf("log endpoint returned %s", resp.Status)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false, fmt.Errorf("log endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if s.config.Kind == ElasticsearchShipper {
+		// The bulk API reports failed documents with a 200 status. Some of the
+		// batch may be indexed, so it is not retried to avoid duplicates.
+		var result struct {
+			Errors bool `json:"errors"`
+		}
+		if err := json.Unmarshal(body, &result); err == nil && result.Errors {
+			return false, errors.New("elasticsearch rejected some documents of the bulk request")
+		}
+	}
+
+	return false, nil
+}
+
+// encode builds the request body for a batch
+func (s *ShipperSink) encode(batch []Entry) ([]byte, error) {
+	if s.config.Kind == ElasticsearchShipper {
+		return s.encodeBulk(batch)
+	}
+	return s.encodeLoki(batch)
+}
+
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// encodeLoki groups the batch into streams by label set. Entries keep their
+// order within a stream, as Loki requires.
+func (s *ShipperSink) encodeLoki(batch []Entry) ([]byte, error) {
+	var push lokiPush
+	streams := make(map[string]int)
+
+	for _, entry := range batch {
+		labels := s.labels(entry)
+		key := labelKey(labels)
+
+		i, ok := streams[key]
+		if !ok {
+			i = len(push.Streams)
+			streams[key] = i
+			push.Streams = append(push.Streams, lokiStream{Stream: labels})
+		}
+
+		push.Streams[i].Values = append(push.Streams[i].Values, [2]string{
+			strconv.FormatInt(entry.Time.UnixNano(), 10),
+			string(bytes.TrimSuffix(formatJSON(entry), []byte("\n"))),
+		})
+	}
+
+	return json.Marshal(push)
+}
+
+// labels returns the Loki stream labels of an entry: the static labels, the
+// level and the configured label fields present in the entry
+func (s *ShipperSink) labels(entry Entry) map[string]string {
+	labels := make(map[string]string, len(s.config.Labels)+len(s.config.LabelFields)+1)
+	for name, value := range s.config.Labels {
+		labels[lokiLabelName(name)] = value
+	}
+	labels["level"] = string(entry.Level)
+	for _, key := range s.config.LabelFields {
+		if value, ok := entry.Fields[key]; ok {
+			labels[lokiLabelName(key)] = fieldString(value)
+		}
+	}
+	return labels
+}
+
+// encodeBulk renders the batch as an Elasticsearch bulk request body
+func (s *ShipperSink) encodeBulk(batch []Entry) ([]byte, error) {
+	action, err := json.Marshal(map[string]interface{}{
+		"index": map[string]string{"_index": s.config.Index},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, entry := range batch {
+		buf.Write(action)
+		buf.WriteByte('\n')
+		buf.Write(bytes.TrimSuffix(formatJSON(entry), []byte("\n")))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// spill stores a batch that couldn't be sent so it can be resent later
+func (s *ShipperSink) spill(payload []byte, count int) {
+	if s.config.SpillDir == "" || s.pending >= maxSpillFiles {
+		s.dropped.Add(uint64(count))
+		return
+	}
+
+	// Zero-padded timestamps keep the files in the order they were spilled
+	name := filepath.Join(s.config.SpillDir, fmt.Sprintf("%020d%s", time.Now().UnixNano(), spillExt))
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, payload, 0o600); err != nil {
+		s.dropped.Add(uint64(count))
+		return
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		_ = os.Remove(tmp)
+		s.dropped.Add(uint64(count))
+		return
+	}
+	s.pending++
+}
+
+// replay resends spilled batches, oldest first, until one fails
+func (s *ShipperSink) replay() {
+	if s.pending == 0 {
+		return
+	}
+
+	names := s.spilled()
+	for i, name := range names {
+		payload, err := os.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		if retryable, err := s.send(payload, false); err != nil && retryable {
+			s.pending = len(names) - i
+			return
+		}
+		// Sent or rejected by the endpoint; either way it's done
+		_ = os.Remove(name)
+	}
+	s.pending = 0
+}
+
+// spilled lists the spilled batch files in the order they were written
+func (s *ShipperSink) spilled() []string {
+	if s.config.SpillDir == "" {
+		return nil
+	}
+	names, err := filepath.Glob(filepath.Join(s.config.SpillDir, "*"+spillExt))
+	if err != nil {
+		return nil
+	}
+	sort.Strings(names)
+	return names
+}
+
+// labelKey identifies a label set
+func labelKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// lokiLabelName converts a key to a valid Prometheus-style label name
+func lokiLabelName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// lokiServer records the pushed bodies and answers with status
+type lokiServer struct {
+	*httptest.Server
+	status atomic.Int32
+
+	mu     sync.Mutex
+	bodies []string
+}
+
+func newLokiServer(t *testing.T) *lokiServer {
+	t.Helper()
+	s := &lokiServer{}
+	s.status.Store(http.StatusNoContent)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+		w.WriteHeader(int(s.status.Load()))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *lokiServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func newTestShipper(t *testing.T, url, spillDir string) *ShipperSink {
+	t.Helper()
+	sink, err := NewShipperSink(ShipperConfig{
+		Kind:          LokiShipper,
+		URL:           url,
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		MaxRetries:    -1,
+		SpillDir:      spillDir,
+	})
+	if err != nil {
+		t.Fatalf("NewShipperSink() error = %v", err)
+	}
+	t.Cleanup(func() { _ = sink.Close() })
+	return sink
+}
+
+func spillFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join(dir, "*"+spillExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestShipperSpillAndReplay(t *testing.T) {
+	server := newLokiServer(t)
+	dir := t.TempDir()
+	sink := newTestShipper(t, server.URL, dir)
+
+	server.status.Store(http.StatusServiceUnavailable)
+	_ = sink.WriteEntry(Entry{Time: time.Now(), Level: InfoLevel, Message: "first"})
+	_ = sink.Sync()
+
+	if got := spillFiles(t, dir); len(got) != 1 {
+		t.Fatalf("spill files = %v, want the failed batch", got)
+	}
+	if sink.pending != 1 {
+		t.Errorf("pending = %d, want 1", sink.pending)
+	}
+
+	// The endpoint is back: the next batch is sent, then the spilled one
+	server.status.Store(http.StatusNoContent)
+	_ = sink.WriteEntry(Entry{Time: time.Now(), Level: InfoLevel, Message: "second"})
+	_ = sink.Sync()
+
+	got := server.requests()
+	if len(got) != 3 || !strings.Contains(got[1], "second") || !strings.Contains(got[2], "first") {
+		t.Fatalf("requests = %v, want the failed one, then second and the replayed first", got)
+	}
+	if files := spillFiles(t, dir); len(files) != 0 {
+		t.Errorf("spill files = %v after replay, want none", files)
+	}
+	if sink.pending != 0 {
+		t.Errorf("pending = %d after replay, want 0", sink.pending)
+	}
+	if sink.Dropped() != 0 {
+		t.Errorf("Dropped() = %d, want 0", sink.Dropped())
+	}
+}
+
+func TestShipperReplayOnlyWhenSpilled(t *testing.T) {
+	server := newLokiServer(t)
+	dir := t.TempDir()
+	sink := newTestShipper(t, server.URL, dir)
+
+	// A file the sink didn't spill itself is not looked for after every batch
+	stray := filepath.Join(dir, "00000000000000000001"+spillExt)
+	if err := os.WriteFile(stray, []byte(`{"streams":[]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = sink.WriteEntry(Entry{Time: time.Now(), Level: InfoLevel, Message: "hello"})
+	_ = sink.Sync()
+
+	if got := server.requests(); len(got) != 1 {
+		t.Errorf("got %d requests, want only the batch", len(got))
+	}
+	if _, err := os.Stat(stray); err != nil {
+		t.Errorf("stray spill file was replayed: %v", err)
+	}
+}
+
+func TestShipperReplaysPreviousRun(t *testing.T) {
+	server := newLokiServer(t)
+	dir := t.TempDir()
+
+	leftover := filepath.Join(dir, "00000000000000000001"+spillExt)
+	if err := os.WriteFile(leftover, []byte(`{"streams":[]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sink := newTestShipper(t, server.URL, dir)
+	_ = sink.WriteEntry(Entry{Time: time.Now(), Level: InfoLevel, Message: "hello"})
+	_ = sink.Sync()
+
+	got := server.requests()
+	if len(got) != 2 || got[1] != `{"streams":[]}` {
+		t.Errorf("requests = %v, want the batch and the leftover spill file", got)
+	}
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Errorf("leftover spill file still exists: %v", err)
+	}
+}
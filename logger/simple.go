@@ -1,7 +1,6 @@
 package logger
 
 import (
-	"fmt"
 	"log"
 	"strings"
 )
@@ -9,8 +8,13 @@ import (
 // simpleLogger is a basic logger implementation using Go's standard log package
 type simpleLogger struct {
 	logger *log.Logger
+	name   string
 	level  int
-	fields map[string]interface{}
+	// format renders entries for the json, logfmt and console formats, and
+	// is nil for text
+	format   func(Entry) []byte
+	fields   map[string]interface{}
+	pipeline *pipeline
 }
 
 const (
@@ -22,51 +26,49 @@ const (
 )
 
 // newSimpleLogger creates a new simple logger
-func newSimpleLogger(config Config) Logger {
-	writer := getWriter(config)
-	level := parseSimpleLevel(config.Level)
+func newSimpleLogger(config Config) (Logger, error) {
+	pipeline, err := newPipeline(config)
+	if err != nil {
+		return nil, err
+	}
+	level := levelRank(pipeline.levels.resolve(""))
 
+	// Formatted entries carry their own timestamp
+	format := entryFormatter(config.Format, pipeline.out.color)
 	var logger *log.Logger
-	if config.Format == "json" {
-		logger = log.New(writer, "", 0)
+	if format != nil {
+		logger = log.New(pipeline.out, "", 0)
 	} else {
-		logger = log.New(writer, "", log.LstdFlags)
+		logger = log.New(pipeline.out, "", log.LstdFlags)
 	}
 
 	return &simpleLogger{
-		logger: logger,
-		level:  level,
-		fields: make(map[string]interface{}),
-	}
+		logger:   logger,
+		level:    level,
+		format:   format,
+		fields:   make(map[string]interface{}),
+		pipeline: pipeline,
+	}, nil
 }
 
 func (s *simpleLogger) Debug(msg string, fields ...interface{}) {
-	if s.level <= levelDebug {
-		s.log("DEBUG", msg, fields...)
-	}
+	s.pipeline.log(s, DebugLevel, msg, fields)
 }
 
 func (s *simpleLogger) Info(msg string, fields ...interface{}) {
-	if s.level <= levelInfo {
-		s.log("INFO", msg, fields...)
-	}
+	s.pipeline.log(s, InfoLevel, msg, fields)
 }
 
 func (s *simpleLogger) Warn(msg string, fields ...interface{}) {
-	if s.level <= levelWarn {
-		s.log("WARN", msg, fields...)
-	}
+	s.pipeline.log(s, WarnLevel, msg, fields)
 }
 
 func (s *simpleLogger) Error(msg string, fields ...interface{}) {
-	if s.level <= levelError {
-		s.log("ERROR", msg, fields...)
-	}
+	s.pipeline.log(s, ErrorLevel, msg, fields)
 }
 
 func (s *simpleLogger) Fatal(msg string, fields ...interface{}) {
-	s.log("FATAL", msg, fields...)
-	log.Fatal(msg)
+	s.pipeline.log(s, FatalLevel, msg, fields)
 }
 
 func (s *simpleLogger) WithField(key string, value interface{}) Logger {
@@ -74,12 +76,15 @@ func (s *simpleLogger) WithField(key string, value interface{}) Logger {
 	for k, v := range s.fields {
 		newFields[k] = v
 	}
-	newFields[key] = value
+	newFields[key] = s.pipeline.field(key, value)
 
 	return &simpleLogger{
-		logger: s.logger,
-		level:  s.level,
-		fields: newFields,
+		logger:   s.logger,
+		name:     s.name,
+		level:    s.level,
+		format:   s.format,
+		fields:   newFields,
+		pipeline: s.pipeline,
 	}
 }
 
@@ -89,45 +94,80 @@ func (s *simpleLogger) WithFields(fields map[string]interface{}) Logger {
 		newFields[k] = v
 	}
 	for k, v := range fields {
-		newFields[k] = v
+		newFields[k] = s.pipeline.field(k, v)
 	}
 
 	return &simpleLogger{
-		logger: s.logger,
-		level:  s.level,
-		fields: newFields,
+		logger:   s.logger,
+		name:     s.name,
+		level:    s.level,
+		format:   s.format,
+		fields:   newFields,
+		pipeline: s.pipeline,
 	}
 }
 
-func (s *simpleLogger) log(level, msg string, fields ...interface{}) {
-	// Combine existing fields with new fields
-	allFields := make(map[string]interface{})
+func (s *simpleLogger) Named(name string) Logger {
+	if name == "" {
+		return s
+	}
+	name = joinName(s.name, name)
+
+	newFields := make(map[string]interface{}, len(s.fields)+1)
 	for k, v := range s.fields {
-		allFields[k] = v
+		newFields[k] = v
 	}
+	newFields[NameField] = name
 
-	// Add new fields from parameters
-	for i := 0; i < len(fields)-1; i += 2 {
-		if key, ok := fields[i].(string); ok && i+1 < len(fields) {
-			allFields[key] = fields[i+1]
-		}
+	return &simpleLogger{
+		logger:   s.logger,
+		name:     name,
+		level:    levelRank(s.pipeline.levels.resolve(name)),
+		format:   s.format,
+		fields:   newFields,
+		pipeline: s.pipeline,
 	}
+}
+
+func (s *simpleLogger) Sync() error {
+	return s.pipeline.sync()
+}
+
+func (s *simpleLogger) Close() error {
+	return s.pipeline.close()
+}
+
+// Dropped returns the number of entries discarded by the async buffer and the sinks
+func (s *simpleLogger) Dropped() uint64 {
+	return s.pipeline.dropped()
+}
+
+func (s *simpleLogger) context() map[string]interface{} {
+	return s.fields
+}
 
-	// Build log message
-	var fieldsStr string
-	if len(allFields) > 0 {
-		var parts []string
-		for k, v := range allFields {
-			parts = append(parts, fmt.Sprintf("%s=%v", k, v))
-		}
-		fieldsStr = " " + strings.Join(parts, " ")
+func (s *simpleLogger) enabled(level LogLevel) bool {
+	return s.level <= parseSimpleLevel(string(level))
+}
+
+func (s *simpleLogger) write(level LogLevel, msg string, fields []interface{}, site callSite) {
+	e := newEntry(s.fields, level, msg, fields, site)
+
+	var logMsg string
+	if s.format != nil {
+		logMsg = string(s.format(e))
+	} else {
+		logMsg = formatText(e)
 	}
 
-	logMsg := fmt.Sprintf("[%s] %s%s", level, msg, fieldsStr)
-	if err := s.logger.Output(3, logMsg); err != nil {
+	if err := s.logger.Output(4, logMsg); err != nil {
 		// Handle output error - could log to stderr or ignore
 		_ = err
 	}
+
+	if level == FatalLevel {
+		s.pipeline.exit(1)
+	}
 }
 
 // parseSimpleLevel converts string level to simple logger level
@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// After a failed reconnect, the syslog and journald sinks drop entries
+// instead of redialing on every call until the delay has passed. The delay
+// doubles up to the maximum.
+const (
+	sinkReconnectDelay    = time.Second
+	sinkMaxReconnectDelay = 30 * time.Second
+)
+
+// errReconnectPending is returned for entries dropped while waiting to reconnect
+var errReconnectPending = errors.New("sink connection lost, waiting to reconnect")
+
+// Entry is a fully processed log entry: redacted, sampled and with the
+// fields of WithField/WithFields merged with those of the logging call
+type Entry struct {
+	Time    time.Time
+	Level   LogLevel
+	Message string
+	// Caller is the "dir/file.go:line" of the logging call, if enabled
+	Caller string
+	// Stack is the stack trace captured for Error and Fatal entries, if enabled
+	Stack  string
+	Fields map[string]interface{}
+}
+
+// Sink receives every entry a logger writes. Sinks are used for outputs
+// that need the structured entry rather than a formatted line.
+type Sink interface {
+	WriteEntry(entry Entry) error
+	Sync() error
+	Close() error
+}
+
+// reconnectBackoff throttles reconnecting a sink after a failure and counts
+// the entries dropped meanwhile. Callers serialize calls to reconnect.
+type reconnectBackoff struct {
+	retryAt time.Time
+	delay   time.Duration
+	dropped atomic.Uint64
+}
+
+// reconnect calls connect unless the last attempt failed less than the
+// current delay ago. The entry is counted as dropped when no connection is
+// made.
+func (b *reconnectBackoff) reconnect(connect func() error) error {
+	if time.Now().Before(b.retryAt) {
+		b.dropped.Add(1)
+		return errReconnectPending
+	}
+	if err := connect(); err != nil {
+		b.delay = min(max(2*b.delay, sinkReconnectDelay), sinkMaxReconnectDelay)
+		b.retryAt = time.Now().Add(b.delay)
+		b.dropped.Add(1)
+		return err
+	}
+	b.delay = 0
+	return nil
+}
+
+// newEntry merges context fields with key-value pairs into an Entry
+func newEntry(context map[string]interface{}, level LogLevel, msg string, fields []interface{}, site callSite) Entry {
+	allFields := make(map[string]interface{}, len(context)+len(fields)/2)
+	for k, v := range context {
+		allFields[k] = v
+	}
+	for i := 0; i < len(fields)-1; i += 2 {
+		if key, ok := fields[i].(string); ok {
+			allFields[key] = fields[i+1]
+		}
+	}
+
+	return Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: msg,
+		Caller:  site.caller(),
+		Stack:   site.stack,
+		Fields:  allFields,
+	}
+}
+
+// newOutputSink creates the sink for outputs that are not plain writers
+func newOutputSink(config Config) (Sink, error) {
+	switch config.Output {
+	case "syslog":
+		sink, err := NewSyslogSink(SyslogConfig{
+			Network:  config.SyslogNetwork,
+			Address:  config.SyslogAddress,
+			Facility: config.SyslogFacility,
+			AppName:  config.AppName,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return sink, nil
+	case "journald":
+		sink, err := NewJournaldSink(config.JournaldSocket, config.AppName)
+		if err != nil {
+			return nil, err
+		}
+		return sink, nil
+	default:
+		return nil, nil
+	}
+}
+
+// newShipperSink creates the HTTP log shipper when ShipTo is set
+func newShipperSink(config Config) (Sink, error) {
+	if config.ShipTo == "" {
+		return nil, nil
+	}
+
+	labels := map[string]string{"app": config.AppName}
+	if config.AppName == "" {
+		labels["app"] = filepath.Base(os.Args[0])
+	}
+
+	sink, err := NewShipperSink(ShipperConfig{
+		Kind:          ShipperKind(config.ShipTo),
+		URL:           config.ShipURL,
+		Index:         config.ShipIndex,
+		Labels:        labels,
+		LabelFields:   config.ShipLabels,
+		Headers:       config.ShipHeaders,
+		BatchSize:     config.ShipBatchSize,
+		FlushInterval: config.ShipFlushInterval,
+		SpillDir:      config.ShipSpillDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
@@ -0,0 +1,288 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogStructuredDataID is the SD-ID under which fields are sent. 32473 is
+// the enterprise number reserved for documentation by RFC 5612.
+const SyslogStructuredDataID = "fields@32473"
+
+// syslogDialTimeout bounds connecting to a remote syslog server
+const syslogDialTimeout = 5 * time.Second
+
+// syslogSockets are the local syslog daemon sockets tried when no address is set
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogFacilities maps facility names to their RFC 5424 codes
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// SyslogConfig holds the settings of a SyslogSink
+type SyslogConfig struct {
+	// Network is "unixgram", "unix", "udp" or "tcp". When empty the local
+	// daemon socket is used, trying datagram then stream.
+	Network string
+	// Address is a socket path or host:port. When empty the usual local
+	// daemon sockets are tried.
+	Address string
+	// Facility is a facility name such as "user", "daemon" or "local0"
+	Facility string
+	// AppName is the APP-NAME of each message, defaulting to the executable name
+	AppName string
+}
+
+// SyslogSink sends entries to a syslog daemon as RFC 5424 messages with the
+// fields in a structured data element
+type SyslogSink struct {
+	config   SyslogConfig
+	facility int
+	hostname string
+	appName  string
+	pid      int
+
+	mu      sync.Mutex
+	conn    net.Conn
+	network string
+	backoff reconnectBackoff
+}
+
+// NewSyslogSink connects to the syslog daemon described by config
+func NewSyslogSink(config SyslogConfig) (*SyslogSink, error) {
+	facility := syslogFacilities["user"]
+	if config.Facility != "" {
+		code, ok := syslogFacilities[strings.ToLower(config.Facility)]
+		if !ok {
+			return nil, fmt.Errorf("unknown syslog facility: %s", config.Facility)
+		}
+		facility = code
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	appName := config.AppName
+	if appName == "" {
+		appName = filepath.Base(os.Args[0])
+	}
+
+	s := &SyslogSink{
+		config:   config,
+		facility: facility,
+		hostname: syslogHeaderField(hostname, 255),
+		appName:  syslogHeaderField(appName, 48),
+		pid:      os.Getpid(),
+	}
+
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// WriteEntry sends the entry, reconnecting once if the connection was lost.
+// While the daemon stays unreachable, reconnecting is retried with a growing
+// delay and entries are dropped in between, so logging never waits on a
+// dial for every call.
+func (s *SyslogSink) WriteEntry(entry Entry) error {
+	msg := s.format(entry)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil {
+		if err := s.send(msg); err == nil {
+			return nil
+		}
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+
+	if err := s.backoff.reconnect(s.connect); err != nil {
+		return err
+	}
+	return s.send(msg)
+}
+
+// Dropped returns the number of entries discarded while the daemon was
+// unreachable
+func (s *SyslogSink) Dropped() uint64 {
+	return s.backoff.dropped.Load()
+}
+
+// Sync is a no-op since every entry is sent immediately
+func (s *SyslogSink) Sync() error {
+	return nil
+}
+
+// Close closes the connection to the syslog daemon
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// connect dials the configured address, or the local daemon sockets
+func (s *SyslogSink) connect() error {
+	if s.config.Network != "" && s.config.Address != "" {
+		conn, err := net.DialTimeout(s.config.Network, s.config.Address, syslogDialTimeout)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog at %s: %w", s.config.Address, err)
+		}
+		s.conn, s.network = conn, s.config.Network
+		return nil
+	}
+
+	networks := []string{"unixgram", "unix"}
+	if s.config.Network != "" {
+		networks = []string{s.config.Network}
+	}
+	addresses := syslogSockets
+	if s.config.Address != "" {
+		addresses = []string{s.config.Address}
+	}
+
+	for _, address := range addresses {
+		for _, network := range networks {
+			conn, err := net.DialTimeout(network, address, syslogDialTimeout)
+			if err == nil {
+				s.conn, s.network = conn, network
+				return nil
+			}
+		}
+	}
+	return errors.New("failed to connect to local syslog daemon")
+}
+
+// send writes one message using the framing of the connection type
+func (s *SyslogSink) send(msg string) error {
+	var err error
+	switch s.network {
+	case "tcp", "tcp4", "tcp6":
+		// Octet counting framing, RFC 6587
+		_, err = fmt.Fprintf(s.conn, "%d %s", len(msg), msg)
+	case "unix":
+		_, err = s.conn.Write([]byte(msg + "\n"))
+	default:
+		_, err = s.conn.Write([]byte(msg))
+	}
+	return err
+}
+
+// format renders the entry as an RFC 5424 message
+func (s *SyslogSink) format(entry Entry) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d - ",
+		s.facility*8+syslogSeverity(entry.Level),
+		entry.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		s.hostname, s.appName, s.pid)
+
+	fields := textFields(entry.Fields)
+	params := make(map[string]string, len(fields)+1)
+	for key, value := range fields {
+		params[key] = fieldString(value)
+	}
+	if entry.Caller != "" {
+		params["caller"] = entry.Caller
+	}
+
+	if len(params) == 0 {
+		b.WriteString("-")
+	} else {
+		keys := make([]string, 0, len(params))
+		for key := range params {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		b.WriteString("[" + SyslogStructuredDataID)
+		for _, key := range keys {
+			fmt.Fprintf(&b, ` %s="%s"`, syslogParamName(key), syslogParamValue(params[key]))
+		}
+		b.WriteString("]")
+	}
+
+	b.WriteString(" ")
+	b.WriteString(entry.Message)
+	if entry.Stack != "" {
+		b.WriteString("\n")
+		b.WriteString(entry.Stack)
+	}
+
+	return b.String()
+}
+
+// syslogSeverity maps a log level to its RFC 5424 severity
+func syslogSeverity(level LogLevel) int {
+	switch level {
+	case DebugLevel:
+		return 7
+	case InfoLevel:
+		return 6
+	case WarnLevel:
+		return 4
+	case ErrorLevel:
+		return 3
+	case FatalLevel:
+		return 2
+	default:
+		return 5
+	}
+}
+
+// syslogHeaderField keeps printable ASCII without spaces, as header fields require
+func syslogHeaderField(value string, maxLen int) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+	if cleaned == "" {
+		return "-"
+	}
+	if len(cleaned) > maxLen {
+		cleaned = cleaned[:maxLen]
+	}
+	return cleaned
+}
+
+// syslogParamName keeps the characters allowed in an SD-NAME
+func syslogParamName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// syslogParamValue escapes the characters that are special in a PARAM-VALUE
+func syslogParamValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
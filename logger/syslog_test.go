@@ -0,0 +1,226 @@
+package logger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readPacket reads one datagram from conn
+func readPacket(t *testing.T, conn net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 64*1024)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to read datagram: %v", err)
+	}
+	return string(buf[:n])
+}
+
+// listenUnixgram listens on a datagram socket in a temporary directory
+func listenUnixgram(t *testing.T) (net.PacketConn, string) {
+	t.Helper()
+	return listenUnixgramAt(t, filepath.Join(t.TempDir(), "log.sock"))
+}
+
+// listenUnixgramAt listens on a datagram socket at path
+func listenUnixgramAt(t *testing.T, path string) (net.PacketConn, string) {
+	t.Helper()
+	conn, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", path, err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, path
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	sink, err := NewSyslogSink(SyslogConfig{Network: "udp", Address: conn.LocalAddr().String(), Facility: "local0", AppName: "pay ments"})
+	if err != nil {
+		t.Fatalf("NewSyslogSink() error = %v", err)
+	}
+	defer sink.Close()
+
+	entry := Entry{
+		Time:    time.Date(2025, 8, 9, 10, 0, 0, 123456000, time.UTC),
+		Level:   WarnLevel,
+		Message: "slow charge",
+		Caller:  "api/pay.go:30",
+		Fields:  map[string]interface{}{"order_id": "A1", "note": `say "hi" [x]`, "bad key": 1},
+	}
+	if err := sink.WriteEntry(entry); err != nil {
+		t.Fatalf("WriteEntry() error = %v", err)
+	}
+
+	// local0 (16) * 8 + warning (4) = 132
+	want := fmt.Sprintf(`<132>1 2025-08-09T10:00:00.123456Z %s payments %d - [fields@32473 bad_key="1" caller="api/pay.go:30" note="say \"hi\" [x\]" order_id="A1"] slow charge`,
+		syslogHeaderField(localHostname(t), 255), os.Getpid())
+	if got := readPacket(t, conn); got != want {
+		t.Errorf("message = %q, want %q", got, want)
+	}
+}
+
+func TestSyslogSinkWithoutFields(t *testing.T) {
+	s := &SyslogSink{facility: 1, hostname: "host", appName: "app", pid: 7}
+
+	got := s.format(Entry{Time: time.Unix(0, 0).UTC(), Level: ErrorLevel, Message: "boom", Stack: "main.main\n\tmain.go:1"})
+	want := "<11>1 1970-01-01T00:00:00.000000Z host app 7 - - boom\nmain.main\n\tmain.go:1"
+	if got != want {
+		t.Errorf("format() = %q, want %q", got, want)
+	}
+}
+
+func TestSyslogSinkTCPFraming(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var length int
+		r := bufio.NewReader(conn)
+		if _, err := fmt.Fscanf(r, "%d ", &length); err != nil {
+			return
+		}
+		msg := make([]byte, length)
+		if _, err := io.ReadFull(r, msg); err == nil {
+			received <- string(msg)
+		}
+	}()
+
+	sink, err := NewSyslogSink(SyslogConfig{Network: "tcp", Address: ln.Addr().String(), AppName: "app"})
+	if err != nil {
+		t.Fatalf("NewSyslogSink() error = %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.WriteEntry(Entry{Time: time.Now(), Level: InfoLevel, Message: "hello"}); err != nil {
+		t.Fatalf("WriteEntry() error = %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		// The octet count covers exactly the message
+		if !regexp.MustCompile(`^<14>1 \S+ \S+ app \d+ - - hello$`).MatchString(msg) {
+			t.Errorf("message = %q", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received")
+	}
+}
+
+func TestSyslogSinkUnknownFacility(t *testing.T) {
+	if _, err := NewSyslogSink(SyslogConfig{Network: "udp", Address: "127.0.0.1:514", Facility: "nope"}); err == nil {
+		t.Error("NewSyslogSink() accepted an unknown facility")
+	}
+}
+
+func TestSyslogSinkReconnectBackoff(t *testing.T) {
+	conn, path := listenUnixgram(t)
+
+	sink, err := NewSyslogSink(SyslogConfig{Network: "unixgram", Address: path, AppName: "app"})
+	if err != nil {
+		t.Fatalf("NewSyslogSink() error = %v", err)
+	}
+	defer sink.Close()
+
+	entry := Entry{Time: time.Now(), Level: InfoLevel, Message: "hello"}
+	if err := sink.WriteEntry(entry); err != nil {
+		t.Fatalf("WriteEntry() error = %v", err)
+	}
+	readPacket(t, conn)
+
+	// The daemon goes away
+	_ = conn.Close()
+	_ = os.Remove(path)
+
+	if err := sink.WriteEntry(entry); err == nil || errors.Is(err, errReconnectPending) {
+		t.Fatalf("WriteEntry() error = %v, want the reconnect failure", err)
+	}
+	if sink.backoff.delay != sinkReconnectDelay {
+		t.Errorf("delay = %s, want %s", sink.backoff.delay, sinkReconnectDelay)
+	}
+
+	// Within the delay entries are dropped without redialing
+	for i := 0; i < 3; i++ {
+		if err := sink.WriteEntry(entry); !errors.Is(err, errReconnectPending) {
+			t.Fatalf("WriteEntry() error = %v, want errReconnectPending", err)
+		}
+	}
+	if got := sink.Dropped(); got != 4 {
+		t.Errorf("Dropped() = %d, want 4", got)
+	}
+
+	// Once the delay has passed, a failed attempt doubles it
+	sink.backoff.retryAt = time.Time{}
+	if err := sink.WriteEntry(entry); err == nil {
+		t.Fatal("WriteEntry() succeeded without a daemon")
+	}
+	if sink.backoff.delay != 2*sinkReconnectDelay {
+		t.Errorf("delay = %s, want %s", sink.backoff.delay, 2*sinkReconnectDelay)
+	}
+
+	// The daemon is back
+	conn, path = listenUnixgramAt(t, path)
+	sink.backoff.retryAt = time.Time{}
+	if err := sink.WriteEntry(entry); err != nil {
+		t.Fatalf("WriteEntry() after the daemon came back error = %v", err)
+	}
+	if got := readPacket(t, conn); !strings.HasSuffix(got, " hello") {
+		t.Errorf("message = %q", got)
+	}
+	if sink.backoff.delay != 0 {
+		t.Errorf("delay = %s after reconnecting, want it reset", sink.backoff.delay)
+	}
+	if got := sink.Dropped(); got != 5 {
+		t.Errorf("Dropped() = %d, want 5", got)
+	}
+}
+
+func TestReconnectBackoffMaxDelay(t *testing.T) {
+	var b reconnectBackoff
+	fail := func() error { return errors.New("refused") }
+
+	for i := 0; i < 10; i++ {
+		b.retryAt = time.Time{}
+		_ = b.reconnect(fail)
+	}
+	if b.delay != sinkMaxReconnectDelay {
+		t.Errorf("delay = %s, want it capped at %s", b.delay, sinkMaxReconnectDelay)
+	}
+	if b.dropped.Load() != 10 {
+		t.Errorf("dropped = %d, want 10", b.dropped.Load())
+	}
+}
+
+// localHostname returns the hostname as NewSyslogSink resolves it
+func localHostname(t *testing.T) string {
+	t.Helper()
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return "-"
+	}
+	return hostname
+}
@@ -4,87 +4,274 @@ import (
 	"strings"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
 	"go.uber.org/zap/zapcore"
 )
 
 // zapLogger wraps zap.Logger to implement our Logger interface
 type zapLogger struct {
 	logger *zap.Logger
-	sugar  *zap.SugaredLogger
+	// fields mirrors the fields added to logger, which zap doesn't expose
+	fields   map[string]interface{}
+	name     string
+	level    LogLevel
+	pipeline *pipeline
 }
 
 // newZapLogger creates a new zap-based logger
-func newZapLogger(config Config) Logger {
-	// Create encoder config
-	var encoderConfig zapcore.EncoderConfig
-	if config.Format == "json" {
-		encoderConfig = zap.NewProductionEncoderConfig()
-	} else {
-		encoderConfig = zap.NewDevelopmentEncoderConfig()
-		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+func newZapLogger(config Config) (Logger, error) {
+	pipeline, err := newPipeline(config)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create encoder
 	var encoder zapcore.Encoder
-	if config.Format == "json" {
-		encoder = zapcore.NewJSONEncoder(encoderConfig)
-	} else {
+	switch LogFormat(config.Format) {
+	case JSONFormat, LogfmtFormat, ConsoleFormat:
+		encoder = newEntryEncoder(entryFormatter(config.Format, pipeline.out.color))
+	default:
+		encoderConfig := zap.NewDevelopmentEncoderConfig()
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 		encoder = zapcore.NewConsoleEncoder(encoderConfig)
 	}
 
 	// Create writer syncer
-	writer := zapcore.AddSync(getWriter(config))
+	writer := zapcore.AddSync(pipeline.out)
 
-	// Create core
-	core := zapcore.NewCore(encoder, writer, parseZapLevel(config.Level))
+	// Create core. Levels are checked per logger by enabled, so the core
+	// only needs to let through the most verbose one.
+	core := zapcore.NewCore(encoder, writer, parseZapLevel(string(pipeline.levels.min())))
 
-	// Create logger
-	logger := zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
+	// Create logger. Caller and stack trace are filled in by write from
+	// the call site resolved by the pipeline.
+	logger := zap.New(core, zap.WithFatalHook(exitHook{pipeline: pipeline}))
 
 	return &zapLogger{
-		logger: logger,
-		sugar:  logger.Sugar(),
-	}
+		logger:   logger,
+		fields:   make(map[string]interface{}),
+		level:    pipeline.levels.resolve(""),
+		pipeline: pipeline,
+	}, nil
 }
 
 func (z *zapLogger) Debug(msg string, fields ...interface{}) {
-	z.sugar.Debugw(msg, fields...)
+	z.pipeline.log(z, DebugLevel, msg, fields)
 }
 
 func (z *zapLogger) Info(msg string, fields ...interface{}) {
-	z.sugar.Infow(msg, fields...)
+	z.pipeline.log(z, InfoLevel, msg, fields)
 }
 
 func (z *zapLogger) Warn(msg string, fields ...interface{}) {
-	z.sugar.Warnw(msg, fields...)
+	z.pipeline.log(z, WarnLevel, msg, fields)
 }
 
 func (z *zapLogger) Error(msg string, fields ...interface{}) {
-	z.sugar.Errorw(msg, fields...)
+	z.pipeline.log(z, ErrorLevel, msg, fields)
 }
 
 func (z *zapLogger) Fatal(msg string, fields ...interface{}) {
-	z.sugar.Fatalw(msg, fields...)
+	z.pipeline.log(z, FatalLevel, msg, fields)
 }
 
 func (z *zapLogger) WithField(key string, value interface{}) Logger {
-	newLogger := z.logger.With(zap.Any(key, value))
-	return &zapLogger{
-		logger: newLogger,
-		sugar:  newLogger.Sugar(),
-	}
+	return z.WithFields(map[string]interface{}{key: value})
 }
 
 func (z *zapLogger) WithFields(fields map[string]interface{}) Logger {
+	newFields := make(map[string]interface{}, len(z.fields)+len(fields))
+	for k, v := range z.fields {
+		newFields[k] = v
+	}
+
 	zapFields := make([]zap.Field, 0, len(fields))
 	for key, value := range fields {
-		zapFields = append(zapFields, zap.Any(key, value))
+		value = z.pipeline.field(key, value)
+		newFields[key] = value
+		zapFields = append(zapFields, zapField(key, value))
 	}
 
-	newLogger := z.logger.With(zapFields...)
 	return &zapLogger{
-		logger: newLogger,
-		sugar:  newLogger.Sugar(),
+		logger:   z.logger.With(zapFields...),
+		fields:   newFields,
+		name:     z.name,
+		level:    z.level,
+		pipeline: z.pipeline,
+	}
+}
+
+// Named uses zap's own logger name, which the encoder writes under the
+// "logger" key, and mirrors it as a field for the sinks
+func (z *zapLogger) Named(name string) Logger {
+	if name == "" {
+		return z
+	}
+	fullName := joinName(z.name, name)
+
+	newFields := make(map[string]interface{}, len(z.fields)+1)
+	for k, v := range z.fields {
+		newFields[k] = v
+	}
+	newFields[NameField] = fullName
+
+	return &zapLogger{
+		logger:   z.logger.Named(name),
+		fields:   newFields,
+		name:     fullName,
+		level:    z.pipeline.levels.resolve(fullName),
+		pipeline: z.pipeline,
+	}
+}
+
+// Sync flushes zap's buffers along with the output
+func (z *zapLogger) Sync() error {
+	z.pipeline.sampler.flushAll()
+	err := z.logger.Sync()
+	if sinkErr := z.pipeline.sync(); err == nil {
+		err = sinkErr
+	}
+	return err
+}
+
+func (z *zapLogger) Close() error {
+	syncErr := z.Sync()
+	if err := z.pipeline.close(); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+// Dropped returns the number of entries discarded by the async buffer and the sinks
+func (z *zapLogger) Dropped() uint64 {
+	return z.pipeline.dropped()
+}
+
+func (z *zapLogger) context() map[string]interface{} {
+	return z.fields
+}
+
+func (z *zapLogger) enabled(level LogLevel) bool {
+	return levelEnabled(z.level, level)
+}
+
+func (z *zapLogger) write(level LogLevel, msg string, fields []interface{}, site callSite) {
+	ce := z.logger.Check(parseZapLevel(string(level)), msg)
+	if ce == nil {
+		return
+	}
+
+	if site.frame.PC != 0 {
+		ce.Caller = zapcore.NewEntryCaller(site.frame.PC, site.frame.File, site.frame.Line, true)
+	}
+	ce.Stack = site.stack
+
+	ce.Write(zapFields(fields)...)
+}
+
+// zapFields converts key-value pairs to zap fields, accepting zap.Field
+// values directly like the sugared logger does
+func zapFields(pairs []interface{}) []zap.Field {
+	fields := make([]zap.Field, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i++ {
+		switch key := pairs[i].(type) {
+		case zap.Field:
+			fields = append(fields, key)
+		case string:
+			if i+1 < len(pairs) {
+				fields = append(fields, zapField(key, pairs[i+1]))
+				i++
+			}
+		default:
+			// Skip the value of a pair with an invalid key
+			i++
+		}
+	}
+	return fields
+}
+
+// zapField wraps zap.Any, which would otherwise log ErrorInfo through its
+// String method instead of in structured form
+func zapField(key string, value interface{}) zap.Field {
+	if info, ok := value.(ErrorInfo); ok {
+		return zap.Reflect(key, info)
+	}
+	return zap.Any(key, value)
+}
+
+// exitHook terminates the process through the pipeline after a fatal entry
+type exitHook struct {
+	pipeline *pipeline
+}
+
+func (h exitHook) OnWrite(_ *zapcore.CheckedEntry, _ []zapcore.Field) {
+	h.pipeline.exit(1)
+}
+
+// entryBufferPool provides the buffers returned by entryEncoder
+var entryBufferPool = buffer.NewPool()
+
+// entryEncoder is a zapcore.Encoder rendering entries with the formatters
+// shared by all backends. Fields are collected in the embedded map encoder.
+type entryEncoder struct {
+	*zapcore.MapObjectEncoder
+	format func(Entry) []byte
+}
+
+func newEntryEncoder(format func(Entry) []byte) *entryEncoder {
+	return &entryEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		format:           format,
+	}
+}
+
+func (e *entryEncoder) Clone() zapcore.Encoder {
+	clone := newEntryEncoder(e.format)
+	for key, value := range e.Fields {
+		clone.Fields[key] = value
+	}
+	return clone
+}
+
+func (e *entryEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	enc := e.Clone().(*entryEncoder)
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+	if ent.LoggerName != "" {
+		enc.Fields[NameField] = ent.LoggerName
+	}
+
+	entry := Entry{
+		Time:    ent.Time,
+		Level:   zapToLevel(ent.Level),
+		Message: ent.Message,
+		Stack:   ent.Stack,
+		Fields:  enc.Fields,
+	}
+	if ent.Caller.Defined {
+		entry.Caller = ent.Caller.TrimmedPath()
+	}
+
+	buf := entryBufferPool.Get()
+	buf.Write(e.format(entry))
+	buf.AppendByte('\n')
+	return buf, nil
+}
+
+// zapToLevel converts a zap level to a LogLevel
+func zapToLevel(level zapcore.Level) LogLevel {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return DebugLevel
+	case level == zapcore.InfoLevel:
+		return InfoLevel
+	case level == zapcore.WarnLevel:
+		return WarnLevel
+	case level == zapcore.ErrorLevel:
+		return ErrorLevel
+	default:
+		return FatalLevel
 	}
 }
 